@@ -0,0 +1,277 @@
+// Package audit records who did what to which case in an append-only trail,
+// kept apart from the logs. Every record is chained to the previous one by an
+// HMAC-SHA256 keyed by a secret, so that any change to the trail breaks the
+// chain, and the tip of a file trail is kept apart from it, so that the
+// removal of its last records is detected too.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/case-management-suite/common/ctxutils"
+	"github.com/case-management-suite/common/errs"
+)
+
+// Event is an action taken on a case. The actor and the case default to the
+// user and the case of the context.
+type Event struct {
+	Actor  string
+	Action string
+	CaseID string
+	// Before and After are the statuses of the case around the action.
+	Before string
+	After  string
+}
+
+// Record is an Event as stored in the trail.
+type Record struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Service string    `json:"service,omitempty"`
+	Actor   string    `json:"actor"`
+	Action  string    `json:"action"`
+	CaseID  string    `json:"case_id"`
+	Before  string    `json:"before,omitempty"`
+	After   string    `json:"after,omitempty"`
+	// PrevHash is the Hash of the previous record, empty for the first one.
+	PrevHash string `json:"prev_hash"`
+	// Hash is the HMAC of the other fields, keyed by the key of the trail.
+	Hash string `json:"hash"`
+}
+
+// AuditLogger appends events to an audit trail. It is safe for concurrent use.
+type AuditLogger interface {
+	Log(ctx context.Context, e Event) error
+}
+
+// ErrBroken is wrapped by the errors of Verify when the trail was altered.
+var ErrBroken = errors.New("audit chain broken")
+
+// ErrNotConfigured is returned by Unconfigured.
+var ErrNotConfigured = errors.New("no audit logger configured")
+
+// Discard is an AuditLogger dropping the events.
+var Discard AuditLogger = discard{}
+
+type discard struct{}
+
+func (discard) Log(context.Context, Event) error {
+	return nil
+}
+
+// Unconfigured is the AuditLogger of the services built without one. It
+// fails every event with ErrNotConfigured rather than dropping them.
+var Unconfigured AuditLogger = unconfigured{}
+
+type unconfigured struct{}
+
+func (unconfigured) Log(context.Context, Event) error {
+	return ErrNotConfigured
+}
+
+// newRecord fills in the record of e from ctx, without chaining it.
+func newRecord(ctx context.Context, e Event, now time.Time) (Record, error) {
+	if e.Actor == "" {
+		e.Actor = ctxutils.GetUser(ctx)
+	}
+	if e.CaseID == "" {
+		e.CaseID = ctxutils.GetCaseID(ctx)
+	}
+	var missing []string
+	for _, f := range []struct{ name, value string }{{"action", e.Action}, {"case", e.CaseID}, {"actor", e.Actor}} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return Record{}, errs.WithKind(fmt.Errorf("audit event without %s", strings.Join(missing, ", ")), errs.Validation)
+	}
+	r := Record{
+		Time:   now.UTC(),
+		Actor:  e.Actor,
+		Action: e.Action,
+		CaseID: e.CaseID,
+		Before: e.Before,
+		After:  e.After,
+	}
+	if names := ctxutils.GetServiceNames(ctx); names != nil {
+		r.Service = strings.Join(*names, "/")
+	}
+	return r, nil
+}
+
+// chain is the tip of a trail.
+type chain struct {
+	key  []byte
+	seq  uint64
+	hash string
+}
+
+func newChain(key []byte) (chain, error) {
+	if len(key) == 0 {
+		return chain{}, errors.New("empty audit key")
+	}
+	return chain{key: key}, nil
+}
+
+// seal numbers r after the tip and hashes it.
+func (c chain) seal(r Record) Record {
+	r.Seq = c.seq + 1
+	r.PrevHash = c.hash
+	r.Hash = hashRecord(c.key, r)
+	return r
+}
+
+func (c *chain) advance(r Record) {
+	c.seq, c.hash = r.Seq, r.Hash
+}
+
+func hashRecord(key []byte, r Record) string {
+	r.Hash = ""
+	b, err := json.Marshal(r)
+	if err != nil {
+		// A Record only holds strings, integers and a time.
+		panic(err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Tip is the last record of a trail, signed with the key of the trail. It is
+// kept apart from the trail, so that removing the last records of the trail
+// is detected.
+type Tip struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+	MAC  string `json:"mac"`
+}
+
+func (c chain) tip() Tip {
+	t := Tip{Seq: c.seq, Hash: c.hash}
+	t.MAC = tipMAC(c.key, t)
+	return t
+}
+
+func tipMAC(key []byte, t Tip) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "tip:%d:%s", t.Seq, t.Hash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the chain of the JSON lines of r with key and returns the
+// number of records. The errors of an altered trail wrap ErrBroken.
+func Verify(r io.Reader, key []byte) (int, error) {
+	c, _, err := verify(r, key, 0)
+	return int(c.seq), err
+}
+
+// VerifyTip is Verify that also checks the trail against its tip, which
+// detects the removal of its last records.
+func VerifyTip(r io.Reader, key []byte, tip Tip) (int, error) {
+	c, err := verifyTip(r, key, tip)
+	return int(c.seq), err
+}
+
+func verifyTip(r io.Reader, key []byte, tip Tip) (chain, error) {
+	c, err := newChain(key)
+	if err != nil {
+		return c, err
+	}
+	if !hmac.Equal([]byte(tip.MAC), []byte(tipMAC(key, tip))) {
+		return c, fmt.Errorf("%w: invalid tip signature", ErrBroken)
+	}
+	c, hash, err := verify(r, key, tip.Seq)
+	if err != nil {
+		return c, err
+	}
+	// The records after the tip were appended before a crash left the tip
+	// behind; they are chained all the same.
+	switch {
+	case c.seq < tip.Seq:
+		return c, fmt.Errorf("%w: %d records, the tip is record %d", ErrBroken, c.seq, tip.Seq)
+	case hash != tip.Hash:
+		return c, fmt.Errorf("%w: record %d does not match the tip", ErrBroken, tip.Seq)
+	}
+	return c, nil
+}
+
+// VerifyFile is VerifyTip for the trail at path and its tip, at TipPath.
+func VerifyFile(path string, key []byte) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	tip, err := readTip(TipPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("%w: missing tip %s", ErrBroken, TipPath(path))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return VerifyTip(f, key, tip)
+}
+
+// verify checks the chain of r and returns its tip and the hash of record at.
+func verify(r io.Reader, key []byte, at uint64) (chain, string, error) {
+	c, err := newChain(key)
+	if err != nil {
+		return c, "", err
+	}
+	var hashAt string
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err == io.EOF && len(b) == 0 {
+			return c, hashAt, nil
+		}
+		if err != nil && err != io.EOF {
+			return c, hashAt, err
+		}
+		if err == io.EOF {
+			return c, hashAt, fmt.Errorf("line %d: %w: truncated record", line, ErrBroken)
+		}
+		rec, err := decodeRecord(b)
+		if err != nil {
+			return c, hashAt, fmt.Errorf("line %d: %w: %v", line, ErrBroken, err)
+		}
+		switch {
+		case rec.Seq != c.seq+1:
+			return c, hashAt, fmt.Errorf("line %d: %w: sequence %d follows %d", line, ErrBroken, rec.Seq, c.seq)
+		case rec.PrevHash != c.hash:
+			return c, hashAt, fmt.Errorf("line %d: %w: previous hash does not match record %d", line, ErrBroken, c.seq)
+		case !hmac.Equal([]byte(rec.Hash), []byte(hashRecord(key, rec))):
+			return c, hashAt, fmt.Errorf("line %d: %w: hash does not match the content of record %d", line, ErrBroken, rec.Seq)
+		}
+		c.advance(rec)
+		if rec.Seq == at {
+			hashAt = rec.Hash
+		}
+	}
+}
+
+func decodeRecord(b []byte) (Record, error) {
+	var rec Record
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rec); err != nil {
+		return Record{}, fmt.Errorf("invalid record: %v", err)
+	}
+	if dec.More() {
+		return Record{}, errors.New("invalid record: trailing data")
+	}
+	return rec, nil
+}
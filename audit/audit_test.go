@@ -0,0 +1,223 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/case-management-suite/common/audit"
+	"github.com/case-management-suite/common/ctxutils"
+	"github.com/case-management-suite/common/errs"
+	"go.uber.org/fx"
+)
+
+var key = []byte("audit-test-key")
+
+func caseContext() context.Context {
+	ctx := ctxutils.WithServiceName(context.Background(), "cases")
+	ctx = ctxutils.WithUser(ctx, "alice")
+	return ctxutils.WithCaseID(ctx, "case-1")
+}
+
+func TestMemoryLoggerFillsInContext(t *testing.T) {
+	l := audit.NewMemoryLogger()
+	if err := l.Log(caseContext(), audit.Event{Action: "close", Before: "OPEN", After: "CLOSED"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Log(context.Background(), audit.Event{Actor: "bob", Action: "open", CaseID: "case-2", After: "OPEN"}); err != nil {
+		t.Fatal(err)
+	}
+
+	records := l.ForCase("case-1")
+	if len(records) != 1 {
+		t.Fatalf("records of case-1 = %+v", records)
+	}
+	rec := records[0]
+	if rec.Seq != 1 || rec.Actor != "alice" || rec.Service != "cases" || rec.Before != "OPEN" || rec.After != "CLOSED" {
+		t.Errorf("record = %+v", rec)
+	}
+	if rec.Time.IsZero() || rec.PrevHash != "" || rec.Hash == "" {
+		t.Errorf("record = %+v", rec)
+	}
+	if second := l.Records()[1]; second.Seq != 2 || second.PrevHash != rec.Hash || second.Service != "" {
+		t.Errorf("second record = %+v", second)
+	}
+}
+
+func TestLogRejectsIncompleteEvents(t *testing.T) {
+	l := audit.NewMemoryLogger()
+	err := l.Log(context.Background(), audit.Event{Action: "close"})
+	if err == nil || !strings.Contains(err.Error(), "without case, actor") {
+		t.Fatalf("error = %v", err)
+	}
+	if errs.KindOf(err) != errs.Validation {
+		t.Errorf("kind = %v, want validation", errs.KindOf(err))
+	}
+	if len(l.Records()) != 0 {
+		t.Errorf("records = %+v", l.Records())
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	l := audit.NewMemoryLogger()
+	for _, action := range []string{"open", "assign", "close"} {
+		if err := l.Log(caseContext(), audit.Event{Action: action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trail, err := l.MarshalJSONL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := audit.Verify(bytes.NewReader(trail), l.Key()); err != nil || n != 3 {
+		t.Fatalf("Verify = %d, %v", n, err)
+	}
+	if _, err := audit.Verify(bytes.NewReader(trail), key); !errors.Is(err, audit.ErrBroken) {
+		t.Errorf("Verify with another key = %v, want ErrBroken", err)
+	}
+
+	lines := strings.SplitAfter(string(trail), "\n")
+	tests := map[string]string{
+		"edited":    strings.Replace(string(trail), `"actor":"alice","action":"assign"`, `"actor":"mallory","action":"assign"`, 1),
+		"removed":   lines[0] + lines[2],
+		"reordered": lines[1] + lines[0] + lines[2],
+		"truncated": string(trail[:len(trail)-10]),
+		"extended":  strings.Replace(string(trail), `"action":"close"`, `"action":"close","note":"x"`, 1),
+	}
+	for name, trail := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := audit.Verify(strings.NewReader(trail), l.Key()); !errors.Is(err, audit.ErrBroken) {
+				t.Errorf("Verify error = %v, want ErrBroken", err)
+			}
+		})
+	}
+}
+
+func TestFileLoggerResumesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "cases.jsonl")
+	for _, action := range []string{"open", "close"} {
+		l, err := audit.OpenFile(path, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Log(caseContext(), audit.Event{Action: action}); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Log(caseContext(), audit.Event{Action: action}); err == nil {
+			t.Error("Log after Close succeeded")
+		}
+	}
+	if n, err := audit.VerifyFile(path, key); err != nil || n != 2 {
+		t.Fatalf("VerifyFile = %d, %v", n, err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bytes.Replace(b, []byte(`"close"`), []byte(`"reopen"`), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := audit.OpenFile(path, key); !errors.Is(err, audit.ErrBroken) {
+		t.Errorf("OpenFile of a tampered trail: %v", err)
+	}
+}
+
+func TestVerifyFileDetectsRemovedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cases.jsonl")
+	l, err := audit.OpenFile(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, action := range []string{"open", "assign", "close"} {
+		if err := l.Log(caseContext(), audit.Event{Action: action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tip := l.Tip(); tip.Seq != 3 || tip.Hash == "" || tip.MAC == "" {
+		t.Errorf("tip = %+v", tip)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	tip, err := os.ReadFile(audit.TipPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct{ trail, tip string }{
+		// The records left still chain, only the tip tells.
+		"last removed": {trail: lines[0] + lines[1], tip: string(tip)},
+		"all removed":  {trail: "", tip: string(tip)},
+		"tip removed":  {trail: string(b)},
+		"tip forged":   {trail: lines[0], tip: strings.Replace(string(tip), `"seq":3`, `"seq":1`, 1)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cut := filepath.Join(t.TempDir(), "cases.jsonl")
+			if err := os.WriteFile(cut, []byte(tt.trail), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.tip != "" {
+				if err := os.WriteFile(audit.TipPath(cut), []byte(tt.tip), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := audit.VerifyFile(cut, key); !errors.Is(err, audit.ErrBroken) {
+				t.Errorf("VerifyFile error = %v, want ErrBroken", err)
+			}
+			if _, err := audit.OpenFile(cut, key); !errors.Is(err, audit.ErrBroken) {
+				t.Errorf("OpenFile error = %v, want ErrBroken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsRehashedRecords(t *testing.T) {
+	// A trail rewritten and hashed again, without the key, does not verify.
+	forger := audit.NewMemoryLogger()
+	if err := forger.Log(caseContext(), audit.Event{Action: "open"}); err != nil {
+		t.Fatal(err)
+	}
+	trail, err := forger.MarshalJSONL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := audit.Verify(bytes.NewReader(trail), key); !errors.Is(err, audit.ErrBroken) {
+		t.Errorf("Verify error = %v, want ErrBroken", err)
+	}
+	if _, err := audit.Verify(bytes.NewReader(trail), nil); err == nil {
+		t.Error("Verify without key succeeded")
+	}
+}
+
+func TestFxAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	var l audit.AuditLogger
+	app := fx.New(fx.NopLogger, audit.FxAudit(path, key), fx.Populate(&l))
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Log(caseContext(), audit.Event{Action: "open"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := audit.VerifyFile(path, key); err != nil || n != 1 {
+		t.Errorf("VerifyFile = %d, %v", n, err)
+	}
+}
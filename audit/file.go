@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileLogger appends the records as JSON lines to a file, synced after every
+// record, then replaces its tip. The file is only ever appended to.
+type FileLogger struct {
+	path string
+
+	mu    sync.Mutex
+	file  *os.File
+	chain chain
+}
+
+// OpenFile opens the trail at path, creating it when missing, and resumes its
+// chain keyed by key. It fails when the trail does not verify against its tip.
+func OpenFile(path string, key []byte) (*FileLogger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	c, err := resume(f, path, key)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("audit trail %s: %w", path, err)
+	}
+	return &FileLogger{path: path, file: f, chain: c}, nil
+}
+
+// resume verifies the trail f against its tip, written for a new trail.
+func resume(f *os.File, path string, key []byte) (chain, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return chain{}, err
+	}
+	tip, err := readTip(TipPath(path))
+	if info.Size() == 0 && errors.Is(err, os.ErrNotExist) {
+		c, err := newChain(key)
+		if err != nil {
+			return c, err
+		}
+		return c, writeTip(path, c.tip())
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return chain{}, fmt.Errorf("%w: missing tip %s", ErrBroken, TipPath(path))
+	}
+	if err != nil {
+		return chain{}, err
+	}
+	return verifyTip(f, key, tip)
+}
+
+// TipPath returns the path of the tip of the trail at path.
+func TipPath(path string) string {
+	return path + ".tip"
+}
+
+// readTip reads the tip at path. A missing tip has the error of os.ReadFile.
+func readTip(path string) (Tip, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Tip{}, err
+	}
+	var tip Tip
+	if err := json.Unmarshal(b, &tip); err != nil {
+		return Tip{}, fmt.Errorf("%w: invalid tip %s: %v", ErrBroken, path, err)
+	}
+	return tip, nil
+}
+
+// writeTip replaces the tip of the trail at path.
+func writeTip(path string, tip Tip) error {
+	b, err := json.Marshal(tip)
+	if err != nil {
+		return err
+	}
+	tmp := TipPath(path) + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(b, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, TipPath(path))
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+func (l *FileLogger) Log(ctx context.Context, e Event) error {
+	rec, err := newRecord(ctx, e, time.Now())
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("audit trail %s is closed", l.path)
+	}
+	rec = l.chain.seal(rec)
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("cannot append to audit trail %s: %w", l.path, err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("cannot sync audit trail %s: %w", l.path, err)
+	}
+	l.chain.advance(rec)
+	// A tip behind the trail only misses the records appended since, which
+	// the chain still covers.
+	if err := writeTip(l.path, l.chain.tip()); err != nil {
+		return fmt.Errorf("cannot write the tip of audit trail %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// Tip returns the tip of the trail, to keep a copy out of reach of the hosts
+// writing the trail.
+func (l *FileLogger) Tip() Tip {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.chain.tip()
+}
+
+func (l *FileLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,20 @@
+package audit
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+)
+
+// FxAudit provides the AuditLogger of the trail at path keyed by key, closed
+// when the application stops.
+func FxAudit(path string, key []byte) fx.Option {
+	return fx.Provide(func(lc fx.Lifecycle) (AuditLogger, error) {
+		l, err := OpenFile(path, key)
+		if err != nil {
+			return nil, err
+		}
+		lc.Append(fx.Hook{OnStop: func(context.Context) error { return l.Close() }})
+		return l, nil
+	})
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// MemoryLogger keeps the records in memory, for the tests. They are chained
+// with a random key, see Key.
+type MemoryLogger struct {
+	mu      sync.Mutex
+	records []Record
+	chain   chain
+}
+
+func NewMemoryLogger() *MemoryLogger {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return &MemoryLogger{chain: chain{key: key}}
+}
+
+// Key returns the key of the chain, to Verify the records.
+func (l *MemoryLogger) Key() []byte {
+	return append([]byte(nil), l.chain.key...)
+}
+
+func (l *MemoryLogger) Log(ctx context.Context, e Event) error {
+	rec, err := newRecord(ctx, e, time.Now())
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec = l.chain.seal(rec)
+	l.records = append(l.records, rec)
+	l.chain.advance(rec)
+	return nil
+}
+
+// Records returns a copy of the records, oldest first.
+func (l *MemoryLogger) Records() []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Record(nil), l.records...)
+}
+
+// ForCase returns the records of the case caseID, oldest first.
+func (l *MemoryLogger) ForCase(caseID string) []Record {
+	var records []Record
+	for _, rec := range l.Records() {
+		if rec.CaseID == caseID {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+// MarshalJSONL returns the records as the JSON lines of a file trail.
+func (l *MemoryLogger) MarshalJSONL() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range l.Records() {
+		if err := enc.Encode(rec); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+// Command cmsaudit checks the audit trails written by the cases services.
+//
+//	cmsaudit verify -key-file <key> <trail>...
+//
+// verify checks the hash chain of every trail with the key of the services,
+// and the trail against its tip, and exits with status 1 when one of them was
+// altered or cut short.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/case-management-suite/common/audit"
+)
+
+const (
+	exitOK     = 0
+	exitBroken = 1
+	exitError  = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(stderr, "usage: cmsaudit verify -key-file <key> <trail>...")
+		return exitError
+	}
+	return runVerify(args[1:], stdout, stderr)
+}
+
+func runVerify(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cmsaudit verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyFile := fs.String("key-file", "", "File holding the key of the trails")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *keyFile == "" || fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: cmsaudit verify -key-file <key> <trail>...")
+		return exitError
+	}
+	key, err := os.ReadFile(*keyFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "cmsaudit: %v\n", err)
+		return exitError
+	}
+	// Like the file secrets of the configuration.
+	key = []byte(strings.TrimSuffix(strings.TrimSuffix(string(key), "\n"), "\r"))
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		n, err := audit.VerifyFile(path, key)
+		switch {
+		case errors.Is(err, audit.ErrBroken):
+			fmt.Fprintf(stdout, "%s: %v\n", path, err)
+			code = exitBroken
+		case err != nil:
+			fmt.Fprintf(stderr, "cmsaudit: %v\n", err)
+			return exitError
+		default:
+			fmt.Fprintf(stdout, "%s: %d records, chain intact\n", path, n)
+		}
+	}
+	return code
+}
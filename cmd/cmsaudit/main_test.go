@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/case-management-suite/common/audit"
+)
+
+var key = []byte("cmsaudit-test-key")
+
+func writeKey(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.key")
+	if err := os.WriteFile(path, append(key, '\n'), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writeTrail(t *testing.T, actions ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := audit.OpenFile(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, action := range actions {
+		if err := l.Log(context.Background(), audit.Event{Actor: "alice", Action: action, CaseID: "case-1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestVerify(t *testing.T) {
+	keyFile := writeKey(t)
+	intact := writeTrail(t, "open", "close")
+	broken := writeTrail(t, "open", "close")
+	cut := writeTrail(t, "open", "close")
+	b, err := os.ReadFile(broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(broken, bytes.Replace(b, []byte("alice"), []byte("bob"), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = os.ReadFile(cut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cut, b[:bytes.IndexByte(b, '\n')+1], 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"verify", "-key-file", keyFile, intact}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if want := intact + ": 2 records, chain intact\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"verify", "-key-file", keyFile, intact, broken, cut}, &stdout, &stderr); code != exitBroken {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), broken+": line 1: audit chain broken") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), cut+": audit chain broken: 1 records, the tip is record 2") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	keyFile := writeKey(t)
+	trail := writeTrail(t, "open")
+	for _, args := range [][]string{
+		nil,
+		{"verify"},
+		{"show"},
+		{"verify", trail},
+		{"verify", "-key-file", filepath.Join(t.TempDir(), "missing.key"), trail},
+		{"verify", "-key-file", keyFile, filepath.Join(t.TempDir(), "missing.jsonl")},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != exitError {
+			t.Errorf("run(%q) = %d, want %d", args, code, exitError)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Command cmsconfig prints the effective configuration of the cases services
+// and compares the configuration of two environments.
+//
+//	cmsconfig [-file cms.yaml] [-format yaml|json] [-env prod] [config flags]
+//	cmsconfig diff [-file cms.yaml] [-color auto|always|never] local test
+//	cmsconfig schema
+//
+// The dump and diff read the CMS_ environment variables like the services do, and
+// redact credentials. diff exits with status 1 when the configurations differ.
+// schema prints the JSON Schema of the configuration files.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/mattn/go-isatty"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	exitOK      = 0
+	exitChanged = 1
+	exitError   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Environ(), os.Stdout, os.Stderr))
+}
+
+func run(args, environ []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "diff":
+			return runDiff(args[1:], environ, stdout, stderr)
+		case "schema":
+			return runSchema(args[1:], stdout, stderr)
+		}
+	}
+	return runDump(args, environ, stdout, stderr)
+}
+
+func runDump(args, environ []string, stdout, stderr io.Writer) int {
+	fs := config.NewFlagSet("cmsconfig", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	file := fs.String("file", "", "Configuration file (.yaml, .yml, .toml or .json)")
+	format := fs.String("format", "yaml", "Output format (yaml or json)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "cmsconfig: unexpected arguments %q\n", fs.Args())
+		return exitError
+	}
+
+	appConfig, _, err := config.Load(config.WithFile(*file), config.WithEnviron(environ), config.WithFlags(fs))
+	if err != nil {
+		fmt.Fprintf(stderr, "cmsconfig: %v\n", err)
+		return exitError
+	}
+	if err := writeConfig(stdout, *format, appConfig.Redacted()); err != nil {
+		fmt.Fprintf(stderr, "cmsconfig: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func writeConfig(w io.Writer, format string, appConfig config.AppConfig) error {
+	switch format {
+	case "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(appConfig); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(appConfig)
+	default:
+		return fmt.Errorf("unknown format %q (valid: yaml, json)", format)
+	}
+}
+
+func runSchema(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		fmt.Fprintf(stderr, "cmsconfig: unexpected arguments %q\n", args)
+		return exitError
+	}
+	schema, err := config.JSONSchema()
+	if err != nil {
+		fmt.Fprintf(stderr, "cmsconfig: %v\n", err)
+		return exitError
+	}
+	fmt.Fprintf(stdout, "%s\n", schema)
+	return exitOK
+}
+
+func runDiff(args, environ []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cmsconfig diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cmsconfig diff [flags] <env> <env>")
+		fs.PrintDefaults()
+	}
+	file := fs.String("file", "", "Configuration file applied to both environments")
+	color := fs.String("color", "auto", "Highlight the changes (auto, always or never)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError
+	}
+	useColor, err := colorEnabled(*color, environ, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "cmsconfig: %v\n", err)
+		return exitError
+	}
+
+	var configs [2]config.AppConfig
+	for i, name := range fs.Args() {
+		if configs[i], err = loadEnv(name, *file, environ); err != nil {
+			fmt.Fprintf(stderr, "cmsconfig: %s: %v\n", name, err)
+			return exitError
+		}
+	}
+
+	changes := config.Diff(configs[0].Redacted(), configs[1].Redacted())
+	if len(changes) == 0 {
+		return exitOK
+	}
+	writeChanges(stdout, changes, useColor)
+	return exitChanged
+}
+
+// loadEnv loads the configuration of the named environment. CMS_ENV is ignored
+// since the environment is given on the command line.
+func loadEnv(name, file string, environ []string) (config.AppConfig, error) {
+	env, err := config.ParseEnvType(name)
+	if err != nil {
+		return config.AppConfig{}, err
+	}
+	base, err := config.ForEnv(env)
+	if err != nil {
+		return config.AppConfig{}, err
+	}
+	envVar := config.EnvVarName(config.DefaultEnvPrefix, []string{"Env"}) + "="
+	filtered := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, envVar) {
+			filtered = append(filtered, kv)
+		}
+	}
+	appConfig, _, err := config.Load(config.WithBase(base), config.WithFile(file), config.WithEnviron(filtered))
+	return appConfig, err
+}
+
+func colorEnabled(mode string, environ []string, w io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		for _, kv := range environ {
+			if strings.HasPrefix(kv, "NO_COLOR=") && kv != "NO_COLOR=" {
+				return false, nil
+			}
+		}
+		f, ok := w.(*os.File)
+		return ok && isatty.IsTerminal(f.Fd()), nil
+	default:
+		return false, fmt.Errorf("unknown color mode %q (valid: auto, always, never)", mode)
+	}
+}
+
+const (
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorReset = "\x1b[0m"
+)
+
+func writeChanges(w io.Writer, changes []config.Change, useColor bool) {
+	rows := make([][3]string, len(changes))
+	var pathWidth, oldWidth int
+	for i, c := range changes {
+		rows[i] = [3]string{c.Path, formatValue(c.Old), formatValue(c.New)}
+		if n := len(rows[i][0]); n > pathWidth {
+			pathWidth = n
+		}
+		if n := len(rows[i][1]); n > oldWidth {
+			oldWidth = n
+		}
+	}
+	for _, row := range rows {
+		old := fmt.Sprintf("%-*s", oldWidth, row[1])
+		updated := row[2]
+		if useColor {
+			old = colorRed + old + colorReset
+			updated = colorGreen + updated + colorReset
+		}
+		fmt.Fprintf(w, "%-*s  %s  %s\n", pathWidth, row[0], old, updated)
+	}
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDumpRedactsCredentials(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-format", "json", "--cases-service.port=9000"}, []string{"CMS_ENV=test"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+
+	var dump map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &dump); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if dump["Env"] != "test" {
+		t.Errorf("Env = %v, want test", dump["Env"])
+	}
+	if port := dump["CasesService"].(map[string]interface{})["Port"]; port != 9000.0 {
+		t.Errorf("CasesService.Port = %v, want 9000", port)
+	}
+	if strings.Contains(stdout.String(), "guest:guest") {
+		t.Errorf("dump leaks the queue password:\n%s", stdout.String())
+	}
+}
+
+func TestDumpRedactsMySQLPassword(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	environ := []string{
+		"CMS_ENV=test",
+		"CMS_CASESSTORAGE_DATABASETYPE=MySQL",
+		"CMS_CASESSTORAGE_ADDRESS=app:hunter2@tcp(db:3306)/cases",
+	}
+	if code := run(nil, environ, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if strings.Contains(stdout.String(), "hunter2") {
+		t.Errorf("dump leaks the MySQL password:\n%s", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "app:xxxxx@tcp(db:3306)/cases") {
+		t.Errorf("dump misses the redacted MySQL address:\n%s", stdout.String())
+	}
+}
+
+func TestDumpYAML(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(nil, []string{"CMS_ENV=local"}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	for _, want := range []string{"env: local", "queuetype: RABBIT_MQ", "xxxxx@"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("dump misses %q:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestDiffEnvironments(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"diff", "-color=never", "local", "test"}, []string{"CMS_ENV=prod"}, &stdout, &stderr)
+	if code != exitChanged {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	var found bool
+	for _, line := range lines {
+		if strings.HasPrefix(line, "RulesServiceConfig.QueueType") {
+			found = true
+			if fields := strings.Fields(line); len(fields) != 3 || fields[1] != "RABBIT_MQ" || fields[2] != "GO_CHANNELS" {
+				t.Errorf("unexpected line %q", line)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("QueueType change not reported:\n%s", stdout.String())
+	}
+	if strings.Contains(stdout.String(), "\x1b[") {
+		t.Error("colors used with -color=never")
+	}
+
+	stdout.Reset()
+	if code := run([]string{"diff", "test", "TEST"}, nil, &stdout, &stderr); code != exitOK || stdout.Len() != 0 {
+		t.Errorf("exit code %d for identical environments:\n%s", code, stdout.String())
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"diff", "local"}, nil, &stdout, &stderr); code != exitError {
+		t.Errorf("exit code %d with a single environment", code)
+	}
+	stderr.Reset()
+	if code := run([]string{"diff", "local", "staging"}, nil, &stdout, &stderr); code != exitError {
+		t.Errorf("exit code %d with an unknown environment", code)
+	}
+	if !strings.Contains(stderr.String(), `unknown environment "staging"`) {
+		t.Errorf("unexpected error output %q", stderr.String())
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"schema"}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &schema); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if schema["title"] != "AppConfig" {
+		t.Errorf("title = %v", schema["title"])
+	}
+}
@@ -0,0 +1,508 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DatabaseType byte
+
+const (
+	Sqlite DatabaseType = iota
+	Postgres
+	MySQL
+)
+
+type DatabaseConfig struct {
+	/*
+		Connection string, still accepted when the section of the DatabaseType is
+		not set. It is parsed into that section by Normalized.
+
+		- For Sqlite: Use path to the *.db file
+		- For Postgres: A connection string like follows
+			"host=localhost user=gorm password=gorm dbname=gorm port=9920 sslmode=disable TimeZone=Asia/Shanghai"
+			or a postgres:// URL
+		- For MySQL: A DSN like "user:password@tcp(localhost:3306)/cases?parseTime=true"
+	*/
+	Address string `redact:"dsn" help:"Connection string, used when the section of the database type is not set"`
+	// Deprecated: register a migrate.Migration and run migrate.Migrator.Migrate instead.
+	CreateSQL    string       `help:"Deprecated, use migrations instead"`
+	DatabaseType DatabaseType `help:"Database engine (Sqlite, Postgres or MySQL)"`
+	LogSQL       bool         `help:"Log every SQL statement"`
+	Postgres     PostgresConfig
+	Sqlite       SqliteConfig
+	MySQL        MySQLConfig `flag:"mysql"`
+	Pool         PoolConfig
+}
+
+type PostgresConfig struct {
+	Host     string `help:"Postgres server host"`
+	Port     int    `help:"Postgres server port"`
+	User     string `help:"Postgres user"`
+	Password string `redact:"full" help:"Postgres password"`
+	DBName   string `flag:"dbname" help:"Postgres database name"`
+	SSLMode  string `flag:"sslmode" help:"Postgres sslmode (disable, allow, prefer, require, verify-ca, verify-full)"`
+	TimeZone string `flag:"timezone" help:"Postgres session time zone"`
+	// Additional key=value parameters, e.g. "connect_timeout=10 application_name=cases".
+	Params string `help:"Additional key=value connection parameters"`
+}
+
+type SqliteConfig struct {
+	Path        string        `help:"Path of the Sqlite database file"`
+	JournalMode string        `help:"Sqlite journal mode (DELETE, TRUNCATE, PERSIST, MEMORY, WAL, OFF)"`
+	BusyTimeout time.Duration `help:"How long Sqlite waits for a locked database"`
+}
+
+type MySQLConfig struct {
+	Host     string `help:"MySQL server host"`
+	Port     int    `help:"MySQL server port"`
+	User     string `help:"MySQL user"`
+	Password string `redact:"full" help:"MySQL password"`
+	DBName   string `flag:"dbname" help:"MySQL database name"`
+	// Query parameters, e.g. "parseTime=true&loc=UTC".
+	Params string `help:"DSN query parameters"`
+}
+
+// PoolConfig tunes the connection pool of a *sql.DB. Zero values keep the
+// database/sql defaults.
+type PoolConfig struct {
+	MaxOpenConns    int           `help:"Maximum number of open connections, 0 for unlimited"`
+	MaxIdleConns    int           `help:"Maximum number of idle connections"`
+	ConnMaxLifetime time.Duration `help:"Maximum time a connection may be reused"`
+	ConnMaxIdleTime time.Duration `help:"Maximum time a connection may be idle"`
+}
+
+func (p PoolConfig) Apply(db *sql.DB) {
+	if p.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if p.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
+
+// DatabaseDriver renders and parses the connection strings of a DatabaseType.
+type DatabaseDriver interface {
+	// Name is the database/sql driver name.
+	Name() string
+	DSN(DatabaseConfig) (string, error)
+	ParseAddress(address string, c *DatabaseConfig) error
+	// Configured tells whether the structured section of the driver is set.
+	Configured(DatabaseConfig) bool
+	// Redact masks the credentials of a connection string of the driver.
+	Redact(address string) string
+}
+
+var databaseDrivers = struct {
+	sync.RWMutex
+	byType map[DatabaseType]DatabaseDriver
+	// names are the configuration names of the types.
+	names map[DatabaseType]string
+}{
+	byType: map[DatabaseType]DatabaseDriver{
+		Sqlite:   sqliteDriver{},
+		Postgres: postgresDriver{},
+		MySQL:    mysqlDriver{},
+	},
+	names: map[DatabaseType]string{
+		Sqlite:   "Sqlite",
+		Postgres: "Postgres",
+		MySQL:    "MySQL",
+	},
+}
+
+// RegisterDatabaseDriver adds support for another DatabaseType, named after
+// the driver in the configuration.
+func RegisterDatabaseDriver(t DatabaseType, d DatabaseDriver) error {
+	databaseDrivers.Lock()
+	defer databaseDrivers.Unlock()
+	if _, ok := databaseDrivers.byType[t]; ok {
+		return fmt.Errorf("database driver for %v is already registered", t)
+	}
+	name := d.Name()
+	if name == "" {
+		return fmt.Errorf("database driver for DatabaseType(%d) has no name", byte(t))
+	}
+	for _, other := range databaseDrivers.names {
+		if strings.EqualFold(other, name) {
+			return fmt.Errorf("database type %s is already registered", other)
+		}
+	}
+	databaseDrivers.byType[t] = d
+	databaseDrivers.names[t] = name
+	return nil
+}
+
+// UnregisterDatabaseDriver removes the driver registered for t, if any.
+func UnregisterDatabaseDriver(t DatabaseType) {
+	databaseDrivers.Lock()
+	defer databaseDrivers.Unlock()
+	delete(databaseDrivers.byType, t)
+	delete(databaseDrivers.names, t)
+}
+
+func lookupDatabaseDriver(t DatabaseType) (DatabaseDriver, error) {
+	databaseDrivers.RLock()
+	defer databaseDrivers.RUnlock()
+	d, ok := databaseDrivers.byType[t]
+	if !ok {
+		return nil, fmt.Errorf("unknown database type %v", t)
+	}
+	return d, nil
+}
+
+func databaseTypeName(t DatabaseType) (string, bool) {
+	databaseDrivers.RLock()
+	defer databaseDrivers.RUnlock()
+	name, ok := databaseDrivers.names[t]
+	return name, ok
+}
+
+// DriverName returns the database/sql driver name for the DatabaseType.
+func (c DatabaseConfig) DriverName() (string, error) {
+	d, err := lookupDatabaseDriver(c.DatabaseType)
+	if err != nil {
+		return "", err
+	}
+	return d.Name(), nil
+}
+
+// Normalized returns a copy where the legacy Address has been parsed into the
+// section of the DatabaseType, unless that section is already set.
+func (c DatabaseConfig) Normalized() (DatabaseConfig, error) {
+	d, err := lookupDatabaseDriver(c.DatabaseType)
+	if err != nil {
+		return c, err
+	}
+	if c.Address == "" || d.Configured(c) {
+		return c, nil
+	}
+	if err := d.ParseAddress(c.Address, &c); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// DSN renders the connection string to pass to sql.Open.
+func (c DatabaseConfig) DSN() (string, error) {
+	n, err := c.Normalized()
+	if err != nil {
+		return "", err
+	}
+	d, err := lookupDatabaseDriver(n.DatabaseType)
+	if err != nil {
+		return "", err
+	}
+	return d.DSN(n)
+}
+
+// redactAddress masks address with the driver of the DatabaseType, or
+// entirely when the type is unknown.
+func (c DatabaseConfig) redactAddress(address string) string {
+	if address == "" {
+		return address
+	}
+	d, err := lookupDatabaseDriver(c.DatabaseType)
+	if err != nil {
+		return redactedValue
+	}
+	return d.Redact(address)
+}
+
+type postgresDriver struct{}
+
+func (postgresDriver) Name() string {
+	return "postgres"
+}
+
+func (postgresDriver) Configured(c DatabaseConfig) bool {
+	return c.Postgres != PostgresConfig{}
+}
+
+func (postgresDriver) DSN(c DatabaseConfig) (string, error) {
+	p := c.Postgres
+	var parts []string
+	add := func(key, value string) {
+		if value != "" {
+			parts = append(parts, key+"="+quotePostgresValue(value))
+		}
+	}
+	add("host", p.Host)
+	if p.Port != 0 {
+		add("port", strconv.Itoa(p.Port))
+	}
+	add("user", p.User)
+	add("password", p.Password)
+	add("dbname", p.DBName)
+	add("sslmode", p.SSLMode)
+	add("TimeZone", p.TimeZone)
+	if p.Params != "" {
+		parts = append(parts, p.Params)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func (postgresDriver) Redact(address string) string {
+	return redactString("dsn", address)
+}
+
+func quotePostgresValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	return "'" + strings.ReplaceAll(v, `'`, `\'`) + "'"
+}
+
+func (postgresDriver) ParseAddress(address string, c *DatabaseConfig) error {
+	var values map[string]string
+	var err error
+	if strings.HasPrefix(address, "postgres://") || strings.HasPrefix(address, "postgresql://") {
+		values, err = parsePostgresURL(address)
+	} else {
+		values, err = parsePostgresKeyValues(address)
+	}
+	if err != nil {
+		return err
+	}
+
+	var p PostgresConfig
+	var params []string
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := values[k]
+		switch strings.ToLower(k) {
+		case "host":
+			p.Host = v
+		case "port":
+			if p.Port, err = strconv.Atoi(v); err != nil {
+				return fmt.Errorf("invalid port %q", v)
+			}
+		case "user":
+			p.User = v
+		case "password":
+			p.Password = v
+		case "dbname":
+			p.DBName = v
+		case "sslmode":
+			p.SSLMode = v
+		case "timezone":
+			p.TimeZone = v
+		default:
+			params = append(params, k+"="+quotePostgresValue(v))
+		}
+	}
+	p.Params = strings.Join(params, " ")
+	c.Postgres = p
+	return nil
+}
+
+func parsePostgresURL(address string) (map[string]string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	if u.Hostname() != "" {
+		values["host"] = u.Hostname()
+	}
+	if u.Port() != "" {
+		values["port"] = u.Port()
+	}
+	if u.User != nil {
+		values["user"] = u.User.Username()
+		if pw, ok := u.User.Password(); ok {
+			values["password"] = pw
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		values["dbname"] = db
+	}
+	for k, v := range u.Query() {
+		if len(v) > 0 {
+			values[k] = v[len(v)-1]
+		}
+	}
+	return values, nil
+}
+
+// parsePostgresKeyValues parses libpq connection strings, where values may be
+// single-quoted with \' and \\ escapes.
+func parsePostgresKeyValues(address string) (map[string]string, error) {
+	values := map[string]string{}
+	s := strings.TrimSpace(address)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("expected key=value in connection string at %q", s)
+		}
+		key := strings.TrimSpace(s[:eq])
+		if strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("expected key=value in connection string at %q", s)
+		}
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value strings.Builder
+		if strings.HasPrefix(s, "'") {
+			i := 1
+			for ; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, fmt.Errorf("unterminated quoted value for %s", key)
+			}
+			s = s[i+1:]
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			value.WriteString(s[:end])
+			s = s[end:]
+		}
+		values[key] = value.String()
+		s = strings.TrimSpace(s)
+	}
+	return values, nil
+}
+
+type sqliteDriver struct{}
+
+func (sqliteDriver) Name() string {
+	return "sqlite3"
+}
+
+func (sqliteDriver) Configured(c DatabaseConfig) bool {
+	return c.Sqlite != SqliteConfig{}
+}
+
+func (sqliteDriver) DSN(c DatabaseConfig) (string, error) {
+	s := c.Sqlite
+	if s.JournalMode == "" && s.BusyTimeout == 0 {
+		return s.Path, nil
+	}
+	q := url.Values{}
+	if s.JournalMode != "" {
+		q.Set("_journal_mode", strings.ToUpper(s.JournalMode))
+	}
+	if s.BusyTimeout != 0 {
+		q.Set("_busy_timeout", strconv.FormatInt(s.BusyTimeout.Milliseconds(), 10))
+	}
+	return "file:" + s.Path + "?" + q.Encode(), nil
+}
+
+var sqlitePasswordPattern = regexp.MustCompile(`([?&]_auth_pass=)[^&]*`)
+
+func (sqliteDriver) Redact(address string) string {
+	return sqlitePasswordPattern.ReplaceAllString(address, "${1}"+redactedValue)
+}
+
+func (sqliteDriver) ParseAddress(address string, c *DatabaseConfig) error {
+	path, query, _ := strings.Cut(strings.TrimPrefix(address, "file:"), "?")
+	s := SqliteConfig{Path: path}
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return err
+	}
+	s.JournalMode = q.Get("_journal_mode")
+	if ms := q.Get("_busy_timeout"); ms != "" {
+		n, err := strconv.Atoi(ms)
+		if err != nil {
+			return fmt.Errorf("invalid _busy_timeout %q", ms)
+		}
+		s.BusyTimeout = time.Duration(n) * time.Millisecond
+	}
+	c.Sqlite = s
+	return nil
+}
+
+type mysqlDriver struct{}
+
+func (mysqlDriver) Name() string {
+	return "mysql"
+}
+
+func (mysqlDriver) Configured(c DatabaseConfig) bool {
+	return c.MySQL != MySQLConfig{}
+}
+
+func (mysqlDriver) DSN(c DatabaseConfig) (string, error) {
+	m := c.MySQL
+	var b strings.Builder
+	if m.User != "" {
+		b.WriteString(m.User)
+		if m.Password != "" {
+			b.WriteString(":" + m.Password)
+		}
+		b.WriteString("@")
+	}
+	host := m.Host
+	if m.Port != 0 {
+		host = net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	}
+	fmt.Fprintf(&b, "tcp(%s)/%s", host, m.DBName)
+	if m.Params != "" {
+		b.WriteString("?" + m.Params)
+	}
+	return b.String(), nil
+}
+
+// Redact masks the password of user:password@, found before the last / like
+// the MySQL driver does, so that passwords may contain : and @.
+func (mysqlDriver) Redact(address string) string {
+	if strings.Contains(address, "://") {
+		return redactURL(address)
+	}
+	end := strings.LastIndexByte(address, '/')
+	if end < 0 {
+		end = len(address)
+	}
+	at := strings.LastIndexByte(address[:end], '@')
+	if at < 0 {
+		return address
+	}
+	colon := strings.IndexByte(address[:at], ':')
+	if colon < 0 {
+		return address
+	}
+	return address[:colon+1] + redactedValue + address[at:]
+}
+
+var mysqlDSNPattern = regexp.MustCompile(`^(?:([^:@]*)(?::(.*))?@)?tcp\(([^)]*)\)/([^?]*)(?:\?(.*))?$`)
+
+func (mysqlDriver) ParseAddress(address string, c *DatabaseConfig) error {
+	m := mysqlDSNPattern.FindStringSubmatch(address)
+	if m == nil {
+		return fmt.Errorf("expected a DSN like user:password@tcp(host:port)/dbname")
+	}
+	cfg := MySQLConfig{User: m[1], Password: m[2], Host: m[3], DBName: m[4], Params: m[5]}
+	if host, port, err := net.SplitHostPort(m[3]); err == nil {
+		cfg.Host = host
+		if cfg.Port, err = strconv.Atoi(port); err != nil {
+			return fmt.Errorf("invalid port %q", port)
+		}
+	}
+	c.MySQL = cfg
+	return nil
+}
@@ -0,0 +1,208 @@
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+)
+
+func TestDatabaseDSN(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.DatabaseConfig
+		driver string
+		dsn    string
+	}{
+		{
+			name: "Postgres",
+			config: config.DatabaseConfig{
+				DatabaseType: config.Postgres,
+				Postgres: config.PostgresConfig{
+					Host: "db", Port: 5432, User: "cms", Password: "it's secret", DBName: "cases",
+					SSLMode: "disable", TimeZone: "Europe/Madrid",
+				},
+			},
+			driver: "postgres",
+			dsn:    `host=db port=5432 user=cms password='it\'s secret' dbname=cases sslmode=disable TimeZone=Europe/Madrid`,
+		},
+		{
+			name:   "SqlitePlainPath",
+			config: config.DatabaseConfig{DatabaseType: config.Sqlite, Sqlite: config.SqliteConfig{Path: "./cases.db"}},
+			driver: "sqlite3",
+			dsn:    "./cases.db",
+		},
+		{
+			name: "SqliteOptions",
+			config: config.DatabaseConfig{
+				DatabaseType: config.Sqlite,
+				Sqlite:       config.SqliteConfig{Path: "/data/cases.db", JournalMode: "wal", BusyTimeout: 5 * time.Second},
+			},
+			driver: "sqlite3",
+			dsn:    "file:/data/cases.db?_busy_timeout=5000&_journal_mode=WAL",
+		},
+		{
+			name: "MySQL",
+			config: config.DatabaseConfig{
+				DatabaseType: config.MySQL,
+				MySQL:        config.MySQLConfig{Host: "db", Port: 3306, User: "cms", Password: "pw", DBName: "cases", Params: "parseTime=true"},
+			},
+			driver: "mysql",
+			dsn:    "cms:pw@tcp(db:3306)/cases?parseTime=true",
+		},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			dsn, err := v.config.DSN()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dsn != v.dsn {
+				t.Errorf("DSN() = %q, want %q", dsn, v.dsn)
+			}
+			driver, err := v.config.DriverName()
+			if err != nil || driver != v.driver {
+				t.Errorf("DriverName() = %q, %v, want %q", driver, err, v.driver)
+			}
+		})
+	}
+}
+
+func TestDatabaseAddressIsParsed(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  config.DatabaseConfig
+		check   func(config.DatabaseConfig) bool
+		wantDSN string
+	}{
+		{
+			name:   "PostgresKeyValues",
+			config: config.DatabaseConfig{DatabaseType: config.Postgres, Address: "host=localhost user=gorm password='a b' dbname=gorm port=9920 sslmode=disable TimeZone=Asia/Shanghai connect_timeout=5"},
+			check: func(c config.DatabaseConfig) bool {
+				p := c.Postgres
+				return p.Host == "localhost" && p.Port == 9920 && p.Password == "a b" && p.TimeZone == "Asia/Shanghai" && p.Params == "connect_timeout=5"
+			},
+			wantDSN: "host=localhost port=9920 user=gorm password='a b' dbname=gorm sslmode=disable TimeZone=Asia/Shanghai connect_timeout=5",
+		},
+		{
+			name:   "PostgresURL",
+			config: config.DatabaseConfig{DatabaseType: config.Postgres, Address: "postgres://cms:pw@db:5433/cases?sslmode=require"},
+			check: func(c config.DatabaseConfig) bool {
+				p := c.Postgres
+				return p.Host == "db" && p.Port == 5433 && p.User == "cms" && p.Password == "pw" && p.DBName == "cases" && p.SSLMode == "require"
+			},
+			wantDSN: "host=db port=5433 user=cms password=pw dbname=cases sslmode=require",
+		},
+		{
+			name:   "Sqlite",
+			config: config.DatabaseConfig{DatabaseType: config.Sqlite, Address: "file:cases.db?_journal_mode=WAL&_busy_timeout=250"},
+			check: func(c config.DatabaseConfig) bool {
+				return c.Sqlite.Path == "cases.db" && c.Sqlite.JournalMode == "WAL" && c.Sqlite.BusyTimeout == 250*time.Millisecond
+			},
+			wantDSN: "file:cases.db?_busy_timeout=250&_journal_mode=WAL",
+		},
+		{
+			name:   "MySQL",
+			config: config.DatabaseConfig{DatabaseType: config.MySQL, Address: "cms:p@ss@tcp(db:3306)/cases"},
+			check: func(c config.DatabaseConfig) bool {
+				m := c.MySQL
+				return m.User == "cms" && m.Password == "p@ss" && m.Host == "db" && m.Port == 3306 && m.DBName == "cases"
+			},
+			wantDSN: "cms:p@ss@tcp(db:3306)/cases",
+		},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			n, err := v.config.Normalized()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !v.check(n) {
+				t.Errorf("unexpected structured form %+v", n)
+			}
+			if dsn, _ := v.config.DSN(); dsn != v.wantDSN {
+				t.Errorf("DSN() = %q, want %q", dsn, v.wantDSN)
+			}
+		})
+	}
+}
+
+func TestValidateDatabaseSections(t *testing.T) {
+	appConfig := config.NewLocalAppConfig()
+	appConfig.CasesStorage = config.DatabaseConfig{
+		DatabaseType: config.Postgres,
+		Postgres:     config.PostgresConfig{Host: "db", SSLMode: "sometimes"},
+		Pool:         config.PoolConfig{MaxOpenConns: 2, MaxIdleConns: 5},
+	}
+	err := appConfig.Validate()
+	verrs, ok := err.(config.ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	want := map[string]bool{
+		"CasesStorage.Postgres.DBName":   true,
+		"CasesStorage.Postgres.SSLMode":  true,
+		"CasesStorage.Pool.MaxIdleConns": true,
+	}
+	if len(verrs) != len(want) {
+		t.Fatalf("got %v", verrs)
+	}
+	for _, fe := range verrs {
+		if !want[fe.Path] {
+			t.Errorf("unexpected error %v", fe)
+		}
+	}
+}
+
+func TestLoadStructuredDatabase(t *testing.T) {
+	appConfig, _, err := config.Load(config.WithEnviron([]string{
+		"CMS_ENV=prod",
+		"CMS_CASESSTORAGE_POSTGRES_HOST=pg.internal",
+		"CMS_CASESSTORAGE_POOL_CONNMAXLIFETIME=30m",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.CasesStorage.Postgres.Host != "pg.internal" || appConfig.CasesStorage.Pool.ConnMaxLifetime != 30*time.Minute {
+		t.Errorf("unexpected database config %+v", appConfig.CasesStorage)
+	}
+}
+
+type clickhouseDriver struct{}
+
+func (clickhouseDriver) Name() string                                      { return "clickhouse" }
+func (clickhouseDriver) DSN(c config.DatabaseConfig) (string, error)       { return c.Address, nil }
+func (clickhouseDriver) ParseAddress(string, *config.DatabaseConfig) error { return nil }
+func (clickhouseDriver) Configured(config.DatabaseConfig) bool             { return false }
+func (clickhouseDriver) Redact(address string) string                      { return address }
+
+func TestRegisteredDatabaseDriverIsNamed(t *testing.T) {
+	const clickhouse = config.DatabaseType(10)
+	if err := config.RegisterDatabaseDriver(clickhouse, clickhouseDriver{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.UnregisterDatabaseDriver(clickhouse) })
+	if err := config.RegisterDatabaseDriver(config.DatabaseType(11), clickhouseDriver{}); err == nil {
+		t.Error("expected a duplicate name to fail")
+	}
+
+	if got, err := config.ParseDatabaseType("ClickHouse"); err != nil || got != clickhouse {
+		t.Errorf("ParseDatabaseType = %v, %v", got, err)
+	}
+	if text, err := clickhouse.MarshalText(); err != nil || string(text) != "clickhouse" {
+		t.Errorf("MarshalText = %q, %v", text, err)
+	}
+
+	path := writeFile(t, "cms.yaml", "casesStorage:\n  databaseType: clickhouse\n  address: tcp://db:9000\n")
+	appConfig, _, err := config.Load(config.WithFile(path), config.WithEnviron([]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.CasesStorage.DatabaseType != clickhouse {
+		t.Fatalf("DatabaseType = %v", appConfig.CasesStorage.DatabaseType)
+	}
+	if _, err := json.Marshal(appConfig.Redacted()); err != nil {
+		t.Errorf("cannot marshal the configuration: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Change is a field whose value differs between two configurations.
+type Change struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// InSection tells whether the change is the section itself or nested in it,
+// e.g. "RulesServiceConfig.QueueConfig.SendRetries" is in "RulesServiceConfig".
+func (c Change) InSection(section string) bool {
+	return section == "" || c.Path == section || strings.HasPrefix(c.Path, section+".")
+}
+
+// Diff lists the fields that changed from old to new, in declaration order.
+// The fields of a named database or queue that was added have a nil Old value,
+// and the ones of a removed instance a nil New value, listed last.
+func Diff(old, new AppConfig) []Change {
+	oldValues := map[string]interface{}{}
+	_ = walkLeaves(reflect.ValueOf(old), nil, func(f leaf) error {
+		oldValues[f.key()] = f.Value.Interface()
+		return nil
+	})
+
+	var changes []Change
+	_ = walkLeaves(reflect.ValueOf(new), nil, func(f leaf) error {
+		key := f.key()
+		newValue := f.Value.Interface()
+		if oldValue := oldValues[key]; !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, Change{Path: key, Old: oldValue, New: newValue})
+		}
+		delete(oldValues, key)
+		return nil
+	})
+	_ = walkLeaves(reflect.ValueOf(old), nil, func(f leaf) error {
+		if oldValue, ok := oldValues[f.key()]; ok {
+			changes = append(changes, Change{Path: f.key(), Old: oldValue})
+		}
+		return nil
+	})
+	return changes
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The enums of the configuration are written by name in files, environment
+// variables, flags and dumps, and parsed case-insensitively.
+
+var apiTypeNames = []string{
+	REST:    "REST",
+	GraphQL: "GraphQL",
+}
+
+// ApiTypes returns every known ApiType.
+func ApiTypes() []ApiType {
+	types := make([]ApiType, len(apiTypeNames))
+	for i := range apiTypeNames {
+		types[i] = ApiType(i)
+	}
+	return types
+}
+
+// DatabaseTypes returns every known DatabaseType, the registered ones
+// included.
+func DatabaseTypes() []DatabaseType {
+	databaseDrivers.RLock()
+	defer databaseDrivers.RUnlock()
+	types := make([]DatabaseType, 0, len(databaseDrivers.names))
+	for t := range databaseDrivers.names {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+func databaseTypeNames() []string {
+	var names []string
+	for _, t := range DatabaseTypes() {
+		name, _ := databaseTypeName(t)
+		names = append(names, name)
+	}
+	return names
+}
+
+// QueueTypes returns every known QueueType.
+func QueueTypes() []QueueType {
+	return []QueueType{RabbitMQ, GoChannels}
+}
+
+// parseEnum returns the index of s in names. The numeric values are still
+// accepted for the configuration files written before the enums had names.
+func parseEnum(kind, s string, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(name, s) {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(s); err == nil && i >= 0 && i < len(names) {
+		return i, nil
+	}
+	return 0, unknownEnumError(kind, s, names)
+}
+
+func unknownEnumError(kind, s string, names []string) error {
+	return fmt.Errorf("unknown %s %q (valid: %s)", kind, s, strings.Join(names, ", "))
+}
+
+func enumName(kind string, i int, names []string) (string, error) {
+	if i < 0 || i >= len(names) {
+		return "", fmt.Errorf("unknown %s %d", kind, i)
+	}
+	return names[i], nil
+}
+
+func ParseApiType(s string) (ApiType, error) {
+	i, err := parseEnum("API type", s, apiTypeNames)
+	return ApiType(i), err
+}
+
+func (t ApiType) String() string {
+	if name, err := enumName("API type", int(t), apiTypeNames); err == nil {
+		return name
+	}
+	return fmt.Sprintf("ApiType(%d)", byte(t))
+}
+
+func (t ApiType) MarshalText() ([]byte, error) {
+	name, err := enumName("API type", int(t), apiTypeNames)
+	return []byte(name), err
+}
+
+func (t *ApiType) UnmarshalText(text []byte) error {
+	v, err := ParseApiType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// ParseDatabaseType also accepts the types of RegisterDatabaseDriver.
+func ParseDatabaseType(s string) (DatabaseType, error) {
+	types := DatabaseTypes()
+	for _, t := range types {
+		if name, _ := databaseTypeName(t); strings.EqualFold(name, s) {
+			return t, nil
+		}
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		for _, t := range types {
+			if int(t) == i {
+				return t, nil
+			}
+		}
+	}
+	return 0, unknownEnumError("database type", s, databaseTypeNames())
+}
+
+func (t DatabaseType) String() string {
+	if name, ok := databaseTypeName(t); ok {
+		return name
+	}
+	return fmt.Sprintf("DatabaseType(%d)", byte(t))
+}
+
+func (t DatabaseType) MarshalText() ([]byte, error) {
+	name, ok := databaseTypeName(t)
+	if !ok {
+		return nil, fmt.Errorf("unknown database type %d", byte(t))
+	}
+	return []byte(name), nil
+}
+
+func (t *DatabaseType) UnmarshalText(text []byte) error {
+	v, err := ParseDatabaseType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+func ParseQueueType(s string) (QueueType, error) {
+	for _, t := range QueueTypes() {
+		if strings.EqualFold(string(t), s) {
+			return t, nil
+		}
+	}
+	return "", unknownEnumError("queue type", s, enumStrings(QueueTypes()))
+}
+
+func (t QueueType) String() string {
+	return string(t)
+}
+
+func (t QueueType) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+func (t *QueueType) UnmarshalText(text []byte) error {
+	v, err := ParseQueueType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// ParseEnvType matches s against the environments with a registered profile.
+func ParseEnvType(s string) (EnvType, error) {
+	envs := EnvTypes()
+	for _, env := range envs {
+		if strings.EqualFold(string(env), s) {
+			return env, nil
+		}
+	}
+	return "", unknownEnumError("environment", s, enumStrings(envs))
+}
+
+func (e EnvType) String() string {
+	return string(e)
+}
+
+func (e EnvType) MarshalText() ([]byte, error) {
+	return []byte(e), nil
+}
+
+func (e *EnvType) UnmarshalText(text []byte) error {
+	v, err := ParseEnvType(string(text))
+	if err != nil {
+		return err
+	}
+	*e = v
+	return nil
+}
+
+func enumStrings[T ~string](values []T) []string {
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = string(v)
+	}
+	return names
+}
+
+func isKnown[T comparable](value T, known []T) bool {
+	for _, k := range known {
+		if k == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package config_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/case-management-suite/common/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseEnums(t *testing.T) {
+	if v, err := config.ParseApiType("graphql"); err != nil || v != config.GraphQL {
+		t.Errorf("ParseApiType(graphql) = %v, %v", v, err)
+	}
+	if v, err := config.ParseDatabaseType("POSTGRES"); err != nil || v != config.Postgres {
+		t.Errorf("ParseDatabaseType(POSTGRES) = %v, %v", v, err)
+	}
+	if v, err := config.ParseDatabaseType("2"); err != nil || v != config.MySQL {
+		t.Errorf("ParseDatabaseType(2) = %v, %v", v, err)
+	}
+	if v, err := config.ParseQueueType("go_channels"); err != nil || v != config.GoChannels {
+		t.Errorf("ParseQueueType(go_channels) = %v, %v", v, err)
+	}
+	if v, err := config.ParseEnvType("Prod"); err != nil || v != config.Env.Prod {
+		t.Errorf("ParseEnvType(Prod) = %v, %v", v, err)
+	}
+
+	for _, c := range []struct {
+		parse func() error
+		want  string
+	}{
+		{func() error { _, err := config.ParseApiType("SOAP"); return err }, `unknown API type "SOAP" (valid: REST, GraphQL)`},
+		{func() error { _, err := config.ParseDatabaseType("oracle"); return err }, `unknown database type "oracle" (valid: Sqlite, Postgres, MySQL)`},
+		{func() error { _, err := config.ParseDatabaseType("3"); return err }, `unknown database type "3"`},
+		{func() error { _, err := config.ParseQueueType("KAFKA"); return err }, `unknown queue type "KAFKA" (valid: RABBIT_MQ, GO_CHANNELS)`},
+		{func() error { _, err := config.ParseEnvType("qa"); return err }, `unknown environment "qa" (valid: `},
+	} {
+		if err := c.parse(); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("got error %v, want %q", err, c.want)
+		}
+	}
+}
+
+func TestEnumsMarshalByName(t *testing.T) {
+	storage := config.DatabaseConfig{DatabaseType: config.MySQL}
+	api := config.APIConfig{APIType: config.GraphQL}
+
+	data, err := json.Marshal(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"APIType":"GraphQL"}` {
+		t.Errorf("json.Marshal = %s", data)
+	}
+	var decoded config.APIConfig
+	if err := json.Unmarshal([]byte(`{"APIType":"rest"}`), &decoded); err != nil || decoded.APIType != config.REST {
+		t.Errorf("json.Unmarshal = %+v, %v", decoded, err)
+	}
+
+	out, err := yaml.Marshal(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "databasetype: MySQL") {
+		t.Errorf("yaml.Marshal = %s", out)
+	}
+	var rules config.RulesServiceConfig
+	if err := yaml.Unmarshal([]byte("queuetype: go_channels\n"), &rules); err != nil || rules.QueueType != config.GoChannels {
+		t.Errorf("yaml.Unmarshal = %+v, %v", rules.QueueType, err)
+	}
+	if err := yaml.Unmarshal([]byte("queuetype: kafka\n"), &rules); err == nil {
+		t.Error("expected an unknown queue type to fail")
+	}
+
+	if s := config.DatabaseType(9).String(); s != "DatabaseType(9)" {
+		t.Errorf("String() = %q", s)
+	}
+	if _, err := json.Marshal(config.APIConfig{APIType: 9}); err == nil {
+		t.Error("expected an unknown API type to fail to marshal")
+	}
+}
+
+func TestLoadEnumsByName(t *testing.T) {
+	path := writeFile(t, "cms.yaml", "api:\n  apiType: graphql\nrulesServiceConfig:\n  queueType: go_channels\n")
+	appConfig, _, err := config.Load(
+		config.WithFile(path),
+		config.WithEnviron([]string{"CMS_ENV=TEST", "CMS_CASESSTORAGE_DATABASETYPE=sqlite"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.Env != config.Env.Test || appConfig.API.APIType != config.GraphQL ||
+		appConfig.RulesServiceConfig.QueueType != config.GoChannels || appConfig.CasesStorage.DatabaseType != config.Sqlite {
+		t.Errorf("unexpected config %+v", appConfig)
+	}
+
+	if _, _, err := config.Load(config.WithEnviron([]string{"CMS_API_APITYPE=soap"})); err == nil {
+		t.Error("expected an unknown API type to fail")
+	}
+}
+
+func TestEnumerations(t *testing.T) {
+	if got := config.ApiTypes(); len(got) != 2 || got[0] != config.REST || got[1] != config.GraphQL {
+		t.Errorf("ApiTypes() = %v", got)
+	}
+	if got := config.DatabaseTypes(); len(got) != 3 || got[2] != config.MySQL {
+		t.Errorf("DatabaseTypes() = %v", got)
+	}
+	if got := config.QueueTypes(); len(got) != 2 {
+		t.Errorf("QueueTypes() = %v", got)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// FeatureConfig sets a feature flag declared with the flags package. The first
+// rule matching the attributes of a request replaces Value and Rollout.
+type FeatureConfig struct {
+	Value   string        `help:"Value of the flag, its declared default when empty"`
+	Rollout Percentage    `help:"Percentage of the cases, by case ID, that get Value; every case when unset"`
+	Rules   []FeatureRule `help:"Overrides for the requests matching an environment, service, tenant or user, by priority"`
+}
+
+// FeatureRule overrides a flag for the requests matching all its non-empty
+// conditions.
+type FeatureRule struct {
+	Name    string     `help:"Name of the rule, reported with the evaluations"`
+	Env     EnvType    `help:"Environment the rule applies to, any when empty"`
+	Service string     `help:"Service the rule applies to, any when empty"`
+	Tenant  string     `help:"Tenant the rule applies to, any when empty"`
+	User    string     `help:"User the rule applies to, any when empty"`
+	Value   string     `help:"Value of the flag for the matching requests"`
+	Rollout Percentage `help:"Percentage of the matching cases that get Value; every case when unset"`
+}
+
+// Percentage is the share of the cases a flag value is rolled out to. The
+// zero Percentage is unset, which rolls out to every case, whereas
+// NewPercentage(0) rolls out to none.
+type Percentage struct {
+	percent int
+	set     bool
+}
+
+func NewPercentage(percent int) Percentage {
+	return Percentage{percent: percent, set: true}
+}
+
+// Get returns the percentage and whether it is set.
+func (p Percentage) Get() (int, bool) {
+	return p.percent, p.set
+}
+
+func (p Percentage) String() string {
+	if !p.set {
+		return ""
+	}
+	return strconv.Itoa(p.percent)
+}
+
+func (p Percentage) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText accepts an empty text, which unsets the percentage.
+func (p *Percentage) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*p = Percentage{}
+		return nil
+	}
+	percent, err := strconv.Atoi(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q", text)
+	}
+	*p = NewPercentage(percent)
+	return nil
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields tagged with `config:"-"` are not part of the loadable configuration.
+const configTag = "config"
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+type leaf struct {
+	Path  []string
+	Field reflect.StructField
+	Value reflect.Value
+	// Parent is the struct holding the field.
+	Parent reflect.Value
+}
+
+func (l leaf) key() string {
+	return strings.Join(l.Path, ".")
+}
+
+func skipField(sf reflect.StructField) bool {
+	return !sf.IsExported() || sf.Tag.Get(configTag) == "-"
+}
+
+func isLeafType(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	return t.Kind() != reflect.Struct && !isInstancesType(t) && !isListType(t)
+}
+
+// isInstancesType tells whether t holds named sections, such as Databases.
+func isInstancesType(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.Struct
+}
+
+// isListType tells whether t holds ordered sections, such as the Rules of a
+// FeatureConfig. A list is replaced as a whole by the file that sets it.
+func isListType(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// walkLeaves calls fn for every loadable scalar of the struct v, in declaration order.
+func walkLeaves(v reflect.Value, path []string, fn func(leaf) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if skipField(sf) {
+			continue
+		}
+		p := append(append([]string{}, path...), sf.Name)
+		if isInstancesType(sf.Type) {
+			if err := walkInstances(v.Field(i), p, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if isListType(sf.Type) {
+			if err := walkList(v.Field(i), p, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if !isLeafType(sf.Type) {
+			if err := walkLeaves(v.Field(i), p, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(leaf{Path: p, Field: sf, Value: v.Field(i), Parent: v}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkInstances walks the sections of the map m by name. A settable m is
+// replaced by a copy holding the walked sections, so that fn can modify them
+// without affecting the configurations sharing the map.
+func walkInstances(m reflect.Value, path []string, fn func(leaf) error) error {
+	if m.Len() == 0 {
+		return nil
+	}
+	names := make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+
+	var copied reflect.Value
+	if m.CanSet() {
+		copied = reflect.MakeMapWithSize(m.Type(), m.Len())
+	}
+	for _, name := range names {
+		key := reflect.ValueOf(name).Convert(m.Type().Key())
+		elem := reflect.New(m.Type().Elem()).Elem()
+		elem.Set(m.MapIndex(key))
+		if err := walkLeaves(elem, append(append([]string{}, path...), name), fn); err != nil {
+			return err
+		}
+		if copied.IsValid() {
+			copied.SetMapIndex(key, elem)
+		}
+	}
+	if copied.IsValid() {
+		m.Set(copied)
+	}
+	return nil
+}
+
+// walkList walks the sections of the slice l by index, e.g. Rules.0.Value. A
+// settable l is replaced by a copy, like in walkInstances.
+func walkList(l reflect.Value, path []string, fn func(leaf) error) error {
+	if l.Len() == 0 {
+		return nil
+	}
+	copied := reflect.MakeSlice(l.Type(), l.Len(), l.Len())
+	reflect.Copy(copied, l)
+	if l.CanSet() {
+		l.Set(copied)
+	}
+	for i := 0; i < copied.Len(); i++ {
+		if err := walkLeaves(copied.Index(i), append(append([]string{}, path...), strconv.Itoa(i)), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeKey makes "casesStorage", "cases_storage" and "CASES-STORAGE" equivalent.
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	key = strings.ReplaceAll(key, "_", "")
+	return strings.ReplaceAll(key, "-", "")
+}
+
+func findField(t reflect.Type, key string) (reflect.StructField, bool) {
+	norm := normalizeKey(key)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if skipField(sf) {
+			continue
+		}
+		if normalizeKey(sf.Name) == norm {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// assignString parses s into v, preferring the type's own text unmarshaling.
+func assignString(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// assignValue stores a decoded file value (string, bool or number) into v.
+func assignValue(v reflect.Value, raw interface{}) error {
+	switch r := raw.(type) {
+	case string:
+		return assignString(v, r)
+	case fmt.Stringer:
+		// json.Number and similar textual numbers.
+		return assignString(v, r.String())
+	case bool:
+		if v.Kind() != reflect.Bool {
+			return fmt.Errorf("cannot use boolean for %s", v.Type())
+		}
+		v.SetBool(r)
+		return nil
+	}
+
+	rv := reflect.ValueOf(raw)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return assignString(v, strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return assignString(v, strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+			v.SetFloat(f)
+			return nil
+		}
+		if f != float64(int64(f)) {
+			return fmt.Errorf("cannot use %v for %s", f, v.Type())
+		}
+		return assignString(v, strconv.FormatInt(int64(f), 10))
+	}
+	return fmt.Errorf("cannot use %T for %s", raw, v.Type())
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Struct tags used to derive command-line flags: `flag:"name"` overrides the
+// kebab-case name of a field or section and `help:"..."` documents it.
+const (
+	flagTag = "flag"
+	helpTag = "help"
+)
+
+// fieldFlag is the flag.Value of a configuration field. It only records the
+// raw value, which Load applies on top of the other sources.
+type fieldFlag struct {
+	path  []string
+	typ   reflect.Type
+	value string
+	set   bool
+}
+
+func (f *fieldFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *fieldFlag) Set(s string) error {
+	if err := assignString(reflect.New(f.typ).Elem(), s); err != nil {
+		return err
+	}
+	f.value = s
+	f.set = true
+	return nil
+}
+
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.typ.Kind() == reflect.Bool
+}
+
+// kebabCase turns field names into flag names: "SendRetries" becomes
+// "send-retries" and "APIType" becomes "api-type".
+func kebabCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && unicode.IsLower(runes[i-1])
+			acronymEnd := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				b.WriteByte('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func flagSegment(sf reflect.StructField) string {
+	if name := sf.Tag.Get(flagTag); name != "" {
+		return name
+	}
+	return kebabCase(sf.Name)
+}
+
+func formatValue(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// RegisterFlags adds a flag for every field of the configuration tree to fs,
+// e.g. --cases-service.port or --rules-service.queue-config.send-retries.
+// The help text comes from the `help` struct tags and the displayed defaults
+// from defaults, with credentials redacted. Pass fs to Load with WithFlags
+// once it has been parsed. The named Databases and Queues and the lists have no flags.
+func RegisterFlags(fs *flag.FlagSet, defaults AppConfig) {
+	type section struct {
+		v     reflect.Value
+		path  []string
+		names []string
+	}
+	var walk func(s section)
+	walk = func(s section) {
+		t := s.v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if skipField(sf) {
+				continue
+			}
+			path := append(append([]string{}, s.path...), sf.Name)
+			names := append(append([]string{}, s.names...), flagSegment(sf))
+			if isInstancesType(sf.Type) || isListType(sf.Type) {
+				continue
+			}
+			if !isLeafType(sf.Type) {
+				walk(section{v: s.v.Field(i), path: path, names: names})
+				continue
+			}
+			value := formatValue(s.v.Field(i))
+			if mode, ok := sf.Tag.Lookup(redactTag); ok {
+				value = redactString(mode, value)
+			}
+			usage := sf.Tag.Get(helpTag)
+			usage = strings.TrimSpace(usage + " (env " + EnvVarName(DefaultEnvPrefix, path) + ")")
+			fs.Var(&fieldFlag{path: path, typ: sf.Type, value: value}, strings.Join(names, "."), usage)
+		}
+	}
+	walk(section{v: reflect.ValueOf(defaults)})
+}
+
+// NewFlagSet returns a flag set with the flags of RegisterFlags, showing the
+// defaults of the local profile.
+func NewFlagSet(name string, errorHandling flag.ErrorHandling) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, errorHandling)
+	RegisterFlags(fs, NewLocalAppConfig())
+	return fs
+}
+
+// WithFlags applies the flags of fs that were set on the command line, on top
+// of every other source.
+func WithFlags(fs *flag.FlagSet) LoadOption {
+	return func(l *loader) {
+		l.flags = fs
+	}
+}
+
+func setFlags(fs *flag.FlagSet) map[string]*fieldFlag {
+	flags := map[string]*fieldFlag{}
+	if fs == nil {
+		return flags
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if ff, ok := f.Value.(*fieldFlag); ok && ff.set {
+			flags[f.Name] = ff
+		}
+	})
+	return flags
+}
+
+func applyFlags(root reflect.Value, fs *flag.FlagSet, sources Sources) error {
+	for name, ff := range setFlags(fs) {
+		v, err := fieldByPath(root, ff.path)
+		if err != nil {
+			return err
+		}
+		if err := assignString(v, ff.value); err != nil {
+			return fmt.Errorf("--%s: %w", name, err)
+		}
+		sources[strings.Join(ff.path, ".")] = Source{Kind: SourceFlag, Name: "--" + name}
+	}
+	return nil
+}
+
+func fieldByPath(v reflect.Value, path []string) (reflect.Value, error) {
+	for i, name := range path {
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			return reflect.Value{}, fmt.Errorf("unknown field %s", strings.Join(path[:i+1], "."))
+		}
+		v = f
+	}
+	return v, nil
+}
@@ -1,7 +1,43 @@
 package config
 
-import "go.uber.org/fx"
+import (
+	"fmt"
 
+	"go.uber.org/fx"
+)
+
+// FxConfig supplies the AppConfig to the application, along with its named
+// databases and queues, failing the fx.App when the configuration is invalid.
 func FxConfig(appConfig AppConfig) fx.Option {
-	return fx.Options(fx.Supply(appConfig))
+	if err := appConfig.Validate(); err != nil {
+		return fx.Error(err)
+	}
+	return fx.Options(fx.Supply(appConfig), FxInstances(appConfig))
+}
+
+// InstanceTag is the fx name tag of a named database or queue, for use with
+// fx.ParamTags, e.g. fx.ParamTags(config.InstanceTag("cases-replica")).
+func InstanceTag(name string) string {
+	return fmt.Sprintf("name:%q", name)
+}
+
+// FxInstances provides every DatabaseConfig of Databases and every
+// QueueConnectionConfig of Queues, named after its key.
+func FxInstances(appConfig AppConfig) fx.Option {
+	var opts []fx.Option
+	for _, name := range sortedKeys(appConfig.Databases) {
+		db := appConfig.Databases[name]
+		opts = append(opts, fx.Provide(fx.Annotate(
+			func() DatabaseConfig { return db },
+			fx.ResultTags(InstanceTag(name)),
+		)))
+	}
+	for _, name := range sortedKeys(appConfig.Queues) {
+		queue := appConfig.Queues[name]
+		opts = append(opts, fx.Provide(fx.Annotate(
+			func() QueueConnectionConfig { return queue },
+			fx.ResultTags(InstanceTag(name)),
+		)))
+	}
+	return fx.Options(opts...)
 }
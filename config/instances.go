@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// instanceNamePattern restricts the names of Databases and Queues to what maps
+// unambiguously to environment variables: CMS_DATABASES_CASES_REPLICA_ADDRESS
+// sets the Address of Databases["cases-replica"].
+var instanceNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Database returns the entry of Databases with the given name.
+func (c AppConfig) Database(name string) (DatabaseConfig, error) {
+	db, ok := c.Databases[name]
+	if !ok {
+		return DatabaseConfig{}, unknownInstanceError("database", name, sortedKeys(c.Databases))
+	}
+	return db, nil
+}
+
+// Queue returns the entry of Queues with the given name.
+func (c AppConfig) Queue(name string) (QueueConnectionConfig, error) {
+	q, ok := c.Queues[name]
+	if !ok {
+		return QueueConnectionConfig{}, unknownInstanceError("queue", name, sortedKeys(c.Queues))
+	}
+	return q, nil
+}
+
+func unknownInstanceError(kind, name string, names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("unknown %s %q, none is configured", kind, name)
+	}
+	return fmt.Errorf("unknown %s %q (configured: %s)", kind, name, strings.Join(names, ", "))
+}
+
+// envInstanceName turns the name part of an environment variable back into an
+// instance name: CASES_REPLICA becomes cases-replica.
+func envInstanceName(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, "_", "-"))
+}
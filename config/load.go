@@ -0,0 +1,470 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultEnvPrefix = "CMS_"
+
+type SourceKind string
+
+const (
+	SourceDefault  SourceKind = "default"
+	SourceFile     SourceKind = "file"
+	SourceEnv      SourceKind = "env"
+	SourceFlag     SourceKind = "flag"
+	SourceProvider SourceKind = "provider"
+)
+
+// Source tells where the value of a configuration field came from, e.g. the
+// file it was read from or the environment variable that overrode it.
+type Source struct {
+	Kind SourceKind
+	Name string
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return string(s.Kind)
+	}
+	return string(s.Kind) + ":" + s.Name
+}
+
+// Sources maps dotted field paths such as "RulesServiceConfig.QueueConfig.Address"
+// to the source of their value.
+type Sources map[string]Source
+
+func (s Sources) Of(path string) Source {
+	if src, ok := s[path]; ok {
+		return src
+	}
+	return Source{Kind: SourceDefault}
+}
+
+// Paths returns the field paths in lexical order.
+func (s Sources) Paths() []string {
+	paths := make([]string, 0, len(s))
+	for p := range s {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+type loader struct {
+	env       EnvType
+	base      *AppConfig
+	file      string
+	envPrefix string
+	environ   []string
+	flags     *flag.FlagSet
+	secrets   map[string]SecretProvider
+	providers []providerSource
+	ctx       context.Context
+}
+
+type LoadOption func(*loader)
+
+// WithFile reads a YAML, TOML or JSON file, chosen by its extension.
+func WithFile(path string) LoadOption {
+	return func(l *loader) {
+		l.file = path
+	}
+}
+
+func WithEnvPrefix(prefix string) LoadOption {
+	return func(l *loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithEnviron replaces os.Environ() as the source of environment variables.
+func WithEnviron(environ []string) LoadOption {
+	return func(l *loader) {
+		l.environ = environ
+	}
+}
+
+// WithEnv selects the profile used as defaults when neither the file nor the
+// CMS_ENV variable name an environment.
+func WithEnv(env EnvType) LoadOption {
+	return func(l *loader) {
+		l.env = env
+	}
+}
+
+// WithBase sets the configuration the file and the environment are layered on,
+// instead of the profile of the selected environment.
+func WithBase(appConfig AppConfig) LoadOption {
+	return func(l *loader) {
+		l.base = &appConfig
+	}
+}
+
+// Load builds an AppConfig from defaults, an optional file, providers,
+// environment variables and command-line flags, in increasing order of
+// precedence, resolves secret references such as ${file:/run/secrets/pg} or
+// ${env:PG_PASSWORD} and validates the result.
+// Environment variables are named after the field path, e.g.
+// CMS_CASESSTORAGE_ADDRESS or CMS_RULESSERVICECONFIG_QUEUECONFIG_SENDRETRIES.
+func Load(opts ...LoadOption) (AppConfig, Sources, error) {
+	l := loader{
+		env:       Env.Local,
+		envPrefix: DefaultEnvPrefix,
+		ctx:       context.Background(),
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	if l.environ == nil {
+		l.environ = os.Environ()
+	}
+	providers := map[string]SecretProvider{
+		"file": FileSecretProvider{},
+		"env":  EnvSecretProvider{LookupEnv: l.lookupEnv},
+	}
+	for scheme, p := range l.secrets {
+		providers[scheme] = p
+	}
+
+	var tree map[string]interface{}
+	if l.file != "" {
+		var err error
+		if tree, err = readConfigFile(l.file); err != nil {
+			return AppConfig{}, nil, err
+		}
+	}
+
+	var appConfig AppConfig
+	if l.base != nil {
+		appConfig = *l.base
+	} else {
+		env, err := l.selectEnv(tree)
+		if err != nil {
+			return AppConfig{}, nil, err
+		}
+		if appConfig, err = ForEnv(env); err != nil {
+			return AppConfig{}, nil, err
+		}
+	}
+
+	sources := Sources{}
+	root := reflect.ValueOf(&appConfig).Elem()
+
+	if err := walkLeaves(root, nil, func(f leaf) error {
+		sources[f.key()] = Source{Kind: SourceDefault}
+		return nil
+	}); err != nil {
+		return AppConfig{}, nil, err
+	}
+
+	if tree != nil {
+		src := Source{Kind: SourceFile, Name: l.file}
+		if err := applyTree(root, nil, tree, src, sources); err != nil {
+			return AppConfig{}, nil, fmt.Errorf("config file %s: %w", l.file, err)
+		}
+	}
+
+	for _, ps := range l.providers {
+		if err := applyProvider(l.ctx, root, ps, sources); err != nil {
+			return AppConfig{}, nil, err
+		}
+	}
+
+	if err := applyEnv(root, l.envPrefix, l.environ, sources); err != nil {
+		return AppConfig{}, nil, err
+	}
+
+	if err := applyFlags(root, l.flags, sources); err != nil {
+		return AppConfig{}, nil, err
+	}
+
+	if err := resolveSecrets(root, providers); err != nil {
+		return AppConfig{}, nil, err
+	}
+
+	if err := appConfig.Validate(); err != nil {
+		return AppConfig{}, nil, err
+	}
+	return appConfig, sources, nil
+}
+
+func (l loader) lookupEnv(name string) (string, bool) {
+	for _, kv := range l.environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == name {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// selectEnv picks the profile to start from, with the same precedence as the
+// values: flags, then the environment variable, then the file.
+func (l loader) selectEnv(tree map[string]interface{}) (EnvType, error) {
+	for _, ff := range setFlags(l.flags) {
+		if len(ff.path) == 1 && ff.path[0] == "Env" && ff.value != "" {
+			return ParseEnvType(ff.value)
+		}
+	}
+	if v, ok := l.lookupEnv(EnvVarName(l.envPrefix, []string{"Env"})); ok && v != "" {
+		return ParseEnvType(v)
+	}
+	for k, v := range tree {
+		if s, ok := v.(string); ok && normalizeKey(k) == "env" && s != "" {
+			return ParseEnvType(s)
+		}
+	}
+	return l.env, nil
+}
+
+func readConfigFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tree := map[string]interface{}{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&tree)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q: %s", ext, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return tree, nil
+}
+
+func applyTree(v reflect.Value, path []string, tree map[string]interface{}, src Source, sources Sources) error {
+	keys := make([]string, 0, len(tree))
+	for k := range tree {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		raw := tree[key]
+		sf, ok := findField(v.Type(), key)
+		if !ok {
+			return fmt.Errorf("unknown key %q", strings.Join(append(path, key), "."))
+		}
+		p := append(append([]string{}, path...), sf.Name)
+		fv := v.FieldByIndex(sf.Index)
+		if isInstancesType(sf.Type) {
+			sub, ok := raw.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s: expected a table, got %T", strings.Join(p, "."), raw)
+			}
+			if err := applyInstances(fv, p, sub, src, sources); err != nil {
+				return err
+			}
+			continue
+		}
+		if isListType(sf.Type) {
+			if err := applyList(fv, p, raw, src, sources); err != nil {
+				return err
+			}
+			continue
+		}
+		if !isLeafType(sf.Type) {
+			sub, ok := raw.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s: expected a table, got %T", strings.Join(p, "."), raw)
+			}
+			if err := applyTree(fv, p, sub, src, sources); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := assignValue(fv, raw); err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(p, "."), err)
+		}
+		sources[strings.Join(p, ".")] = src
+	}
+	return nil
+}
+
+// applyInstances applies the sections of tree to the map m, adding the ones
+// it does not hold yet. The map is copied, it may be shared with the base.
+func applyInstances(m reflect.Value, path []string, tree map[string]interface{}, src Source, sources Sources) error {
+	copied := reflect.MakeMapWithSize(m.Type(), m.Len()+len(tree))
+	iter := m.MapRange()
+	for iter.Next() {
+		copied.SetMapIndex(iter.Key(), iter.Value())
+	}
+	for _, name := range sortedKeys(tree) {
+		sub, ok := tree[name].(map[string]interface{})
+		p := append(append([]string{}, path...), name)
+		if !ok {
+			return fmt.Errorf("%s: expected a table, got %T", strings.Join(p, "."), tree[name])
+		}
+		key := reflect.ValueOf(name).Convert(m.Type().Key())
+		elem := reflect.New(m.Type().Elem()).Elem()
+		if existing := copied.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		} else {
+			setDefaultSources(elem, p, sources)
+		}
+		if err := applyTree(elem, p, sub, src, sources); err != nil {
+			return err
+		}
+		copied.SetMapIndex(key, elem)
+	}
+	m.Set(copied)
+	return nil
+}
+
+// applyList replaces the slice l with the sections of raw, a list of tables.
+func applyList(l reflect.Value, path []string, raw interface{}, src Source, sources Sources) error {
+	rv := reflect.ValueOf(raw)
+	if rv.Kind() != reflect.Slice {
+		return fmt.Errorf("%s: expected a list, got %T", strings.Join(path, "."), raw)
+	}
+	prefix := strings.Join(path, ".") + "."
+	for key := range sources {
+		if strings.HasPrefix(key, prefix) {
+			delete(sources, key)
+		}
+	}
+	list := reflect.MakeSlice(l.Type(), rv.Len(), rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		p := append(append([]string{}, path...), strconv.Itoa(i))
+		sub, ok := rv.Index(i).Interface().(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected a table, got %T", strings.Join(p, "."), rv.Index(i).Interface())
+		}
+		setDefaultSources(list.Index(i), p, sources)
+		if err := applyTree(list.Index(i), p, sub, src, sources); err != nil {
+			return err
+		}
+	}
+	l.Set(list)
+	return nil
+}
+
+func setDefaultSources(v reflect.Value, path []string, sources Sources) {
+	_ = walkLeaves(v, path, func(f leaf) error {
+		sources[f.key()] = Source{Kind: SourceDefault}
+		return nil
+	})
+}
+
+// EnvVarName returns the environment variable that overrides the field at path.
+func EnvVarName(prefix string, path []string) string {
+	name := strings.Join(path, "_")
+	return prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func applyEnv(root reflect.Value, prefix string, environ []string, sources Sources) error {
+	vars := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, prefix) {
+			vars[k] = v
+		}
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+	addEnvInstances(root, nil, prefix, vars, sources)
+	return walkLeaves(root, nil, func(f leaf) error {
+		name := EnvVarName(prefix, f.Path)
+		val, ok := vars[name]
+		if !ok {
+			return nil
+		}
+		if err := assignString(f.Value, val); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		sources[f.key()] = Source{Kind: SourceEnv, Name: name}
+		return nil
+	})
+}
+
+// addEnvInstances adds the named sections that only environment variables
+// set, e.g. CMS_DATABASES_CASES_REPLICA_ADDRESS adds Databases["cases-replica"].
+func addEnvInstances(v reflect.Value, path []string, prefix string, vars map[string]string, sources Sources) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if skipField(sf) {
+			continue
+		}
+		p := append(append([]string{}, path...), sf.Name)
+		switch {
+		case isInstancesType(sf.Type):
+			addEnvInstancesTo(v.Field(i), p, prefix, vars, sources)
+		case isListType(sf.Type):
+			// The environment only sets the fields of the existing sections.
+		case !isLeafType(sf.Type):
+			addEnvInstances(v.Field(i), p, prefix, vars, sources)
+		}
+	}
+}
+
+func addEnvInstancesTo(m reflect.Value, path []string, prefix string, vars map[string]string, sources Sources) {
+	elemType := m.Type().Elem()
+	// The variable names end with the path of a field of the section, the
+	// longest match wins so that _POSTGRES_HOST is not read as _HOST.
+	var suffixes []string
+	_ = walkLeaves(reflect.New(elemType).Elem(), nil, func(f leaf) error {
+		suffixes = append(suffixes, "_"+EnvVarName("", f.Path))
+		return nil
+	})
+	sort.Slice(suffixes, func(i, j int) bool { return len(suffixes[i]) > len(suffixes[j]) })
+
+	known := map[string]bool{}
+	for _, k := range m.MapKeys() {
+		known[EnvVarName("", []string{k.String()})] = true
+	}
+	mapPrefix := EnvVarName(prefix, path) + "_"
+	added := map[string]bool{}
+	for name := range vars {
+		rest := strings.TrimPrefix(name, mapPrefix)
+		if rest == name {
+			continue
+		}
+		for _, suffix := range suffixes {
+			if envName := strings.TrimSuffix(rest, suffix); envName != rest && envName != "" {
+				if !known[envName] {
+					added[envName] = true
+				}
+				break
+			}
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+
+	copied := reflect.MakeMapWithSize(m.Type(), m.Len()+len(added))
+	iter := m.MapRange()
+	for iter.Next() {
+		copied.SetMapIndex(iter.Key(), iter.Value())
+	}
+	for envName := range added {
+		name := envInstanceName(envName)
+		elem := reflect.New(elemType).Elem()
+		setDefaultSources(elem, append(append([]string{}, path...), name), sources)
+		copied.SetMapIndex(reflect.ValueOf(name).Convert(m.Type().Key()), elem)
+	}
+	m.Set(copied)
+}
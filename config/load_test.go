@@ -0,0 +1,179 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/rs/zerolog"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var loadFormats = []struct {
+	name    string
+	file    string
+	content string
+}{
+	{
+		name: "YAML",
+		file: "cms.yaml",
+		content: `
+casesService:
+  port: 9000
+casesStorage:
+  address: /var/lib/cms/cases.db
+rulesServiceConfig:
+  queueConfig:
+    sendRetries: 7
+    logLevel: warn
+`,
+	},
+	{
+		name: "TOML",
+		file: "cms.toml",
+		content: `
+[CasesService]
+Port = 9000
+
+[CasesStorage]
+Address = "/var/lib/cms/cases.db"
+
+[RulesServiceConfig.QueueConfig]
+SendRetries = 7
+LogLevel = "warn"
+`,
+	},
+	{
+		name: "JSON",
+		file: "cms.json",
+		content: `{
+  "CasesService": {"Port": 9000},
+  "CasesStorage": {"Address": "/var/lib/cms/cases.db"},
+  "RulesServiceConfig": {"QueueConfig": {"SendRetries": 7, "LogLevel": "warn"}}
+}`,
+	},
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	for _, v := range loadFormats {
+		t.Run(v.name, func(t *testing.T) {
+			path := writeFile(t, v.file, v.content)
+			appConfig, sources, err := config.Load(config.WithFile(path), config.WithEnviron([]string{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if appConfig.CasesService.Port != 9000 {
+				t.Errorf("CasesService.Port = %d, want 9000", appConfig.CasesService.Port)
+			}
+			if appConfig.CasesStorage.Address != "/var/lib/cms/cases.db" {
+				t.Errorf("CasesStorage.Address = %q", appConfig.CasesStorage.Address)
+			}
+			queue := appConfig.RulesServiceConfig.QueueConfig
+			if queue.SendRetries != 7 || queue.LogLevel != zerolog.WarnLevel {
+				t.Errorf("QueueConfig = %+v", queue)
+			}
+			if queue.CaseActionsChannel != "case_action_channel" {
+				t.Errorf("default CaseActionsChannel was not kept: %q", queue.CaseActionsChannel)
+			}
+			if got := sources.Of("RulesServiceConfig.QueueConfig.SendRetries"); got.Kind != config.SourceFile || got.Name != path {
+				t.Errorf("SendRetries source = %v", got)
+			}
+			if got := sources.Of("CasesService.Host"); got.Kind != config.SourceDefault {
+				t.Errorf("CasesService.Host source = %v", got)
+			}
+		})
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "cms.yaml", "casesStorage:\n  address: from-file.db\n")
+	appConfig, sources, err := config.Load(
+		config.WithFile(path),
+		config.WithEnviron([]string{
+			"CMS_CASESSTORAGE_ADDRESS=from-env.db",
+			"CMS_RULESSERVICECONFIG_QUEUECONFIG_PURGEONSTART=true",
+			"OTHER_CASESSTORAGE_ADDRESS=ignored.db",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.CasesStorage.Address != "from-env.db" {
+		t.Errorf("CasesStorage.Address = %q, want from-env.db", appConfig.CasesStorage.Address)
+	}
+	if !appConfig.RulesServiceConfig.QueueConfig.PurgeOnStart {
+		t.Error("PurgeOnStart was not overridden")
+	}
+	want := config.Source{Kind: config.SourceEnv, Name: "CMS_CASESSTORAGE_ADDRESS"}
+	if got := sources.Of("CasesStorage.Address"); got != want {
+		t.Errorf("CasesStorage.Address source = %v, want %v", got, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		environ []string
+	}{
+		{name: "UnknownKey", file: "cms.yaml", content: "casesService:\n  prot: 1\n"},
+		{name: "Overflow", file: "cms.yaml", content: "casesService:\n  port: 70000\n"},
+		{name: "UnknownExtension", file: "cms.ini", content: "port=1"},
+		{name: "BadEnvValue", file: "cms.yaml", content: "", environ: []string{"CMS_CASESSERVICE_PORT=abc"}},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			path := writeFile(t, v.file, v.content)
+			if _, _, err := config.Load(config.WithFile(path), config.WithEnviron(v.environ)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadFeatureRulesInOrder(t *testing.T) {
+	path := writeFile(t, "cms.yaml", `
+features:
+  workflow:
+    value: v1
+    rollout: 0
+    rules:
+      - name: prod
+        env: prod
+        value: v2
+      - name: acme
+        tenant: acme
+        value: v3
+        rollout: 50
+`)
+	appConfig, sources, err := config.Load(config.WithFile(path), config.WithEnviron([]string{"CMS_FEATURES_WORKFLOW_RULES_1_VALUE=v4"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	workflow := appConfig.Features["workflow"]
+	if percent, ok := workflow.Rollout.Get(); !ok || percent != 0 {
+		t.Errorf("Rollout = %v, %v, want a set 0", percent, ok)
+	}
+	if len(workflow.Rules) != 2 || workflow.Rules[0].Name != "prod" || workflow.Rules[1].Name != "acme" {
+		t.Fatalf("Rules = %+v", workflow.Rules)
+	}
+	if rule := workflow.Rules[1]; rule.Value != "v4" || rule.Rollout != config.NewPercentage(50) {
+		t.Errorf("Rules[1] = %+v", rule)
+	}
+	if got := sources.Of("Features.workflow.Rules.1.Value"); got.Kind != config.SourceEnv {
+		t.Errorf("Rules.1.Value source = %v", got)
+	}
+	if got := sources.Of("Features.workflow.Rules.0.Env"); got.Kind != config.SourceFile {
+		t.Errorf("Rules.0.Env source = %v", got)
+	}
+}
@@ -1 +1,155 @@
 package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LogFormat is the encoding of the log lines.
+type LogFormat string
+
+const (
+	LogConsole = LogFormat("console")
+	LogJSON    = LogFormat("json")
+	LogLogfmt  = LogFormat("logfmt")
+)
+
+// LogSinkType is the kind of destination of a LogSinkConfig.
+type LogSinkType string
+
+const (
+	SinkStderr = LogSinkType("stderr")
+	SinkStdout = LogSinkType("stdout")
+	SinkFile   = LogSinkType("file")
+	// SinkUDP sends every line as a syslog datagram.
+	SinkUDP = LogSinkType("udp")
+)
+
+// LogConfig sets up the loggers of the services. The lines go to stderr when
+// no sink is set.
+type LogConfig struct {
+	Format     LogFormat                    `help:"Format of the log lines (console, json or logfmt)"`
+	Level      zerolog.Level                `help:"Minimum level logged (trace, debug, info, warn, error)"`
+	TimeFormat string                       `help:"Go time layout of the timestamps, RFC3339 when empty"`
+	Sinks      map[string]LogSinkConfig     `help:"Destinations of the log lines by name"`
+	Redact     map[string]LogRedactRule     `help:"Rules masking the sensitive fields and values by name"`
+	RedactKey  string                       `redact:"full" help:"Secret keying the HMAC of the Hash redact rules, e.g. ${file:/run/secrets/log-key}"`
+	Components map[string]LogComponentLevel `help:"Levels of the components of the services by name"`
+}
+
+// LogSinkConfig is a destination of the log lines.
+type LogSinkConfig struct {
+	Type    LogSinkType   `help:"Destination (stderr, stdout, file or udp)"`
+	Format  LogFormat     `help:"Format of the lines of this sink, the log format when empty"`
+	Level   zerolog.Level `help:"Minimum level written to this sink"`
+	Path    string        `help:"File the lines are appended to, for the file sink"`
+	Address string        `help:"host:port of the syslog collector, for the udp sink"`
+	// The rotation of the file sink.
+	MaxSizeMB   int           `help:"Size in megabytes at which the file is rotated, never when 0"`
+	RotateEvery time.Duration `help:"Interval at which the file is rotated, e.g. 24h; never when 0"`
+	MaxBackups  int           `help:"Number of rotated files kept, all when 0"`
+	MaxAge      time.Duration `help:"Age after which the rotated files are removed, never when 0"`
+	Compress    bool          `help:"Gzip the rotated files"`
+}
+
+// LogRedactRule masks the fields named Key, at any depth, or the parts of the
+// string values matching Pattern.
+type LogRedactRule struct {
+	Key     string `help:"Field name, matched case-insensitively"`
+	Pattern string `help:"Regular expression matched in the string values"`
+	Hash    bool   `help:"Replace with an HMAC keyed by RedactKey instead of a mask, so that the lines can be correlated"`
+}
+
+// LogComponentLevel sets the level of the components matching Pattern, a
+// dotted component name such as cases.db, a name ending in .* matching it and
+// the names below it, or * for every component.
+type LogComponentLevel struct {
+	Pattern string        `help:"Components the level applies to, e.g. cases.db, cases.* or *"`
+	Level   zerolog.Level `help:"Minimum level logged by the matching components"`
+}
+
+// Patterns of the personal data masked by the profiles.
+const (
+	EmailPattern      = `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`
+	NationalIDPattern = `\b[0-9]{3}-[0-9]{2}-[0-9]{4}\b`
+)
+
+// DefaultRedactRules returns the rules set by the profiles.
+func DefaultRedactRules() map[string]LogRedactRule {
+	return map[string]LogRedactRule{
+		"password":      {Key: "password"},
+		"authorization": {Key: "authorization"},
+		"email":         {Pattern: EmailPattern},
+		"national-id":   {Pattern: NationalIDPattern},
+	}
+}
+
+// LogFormats returns every known LogFormat.
+func LogFormats() []LogFormat {
+	return []LogFormat{LogConsole, LogJSON, LogLogfmt}
+}
+
+// LogSinkTypes returns every known LogSinkType.
+func LogSinkTypes() []LogSinkType {
+	return []LogSinkType{SinkStderr, SinkStdout, SinkFile, SinkUDP}
+}
+
+func ParseLogFormat(s string) (LogFormat, error) {
+	for _, f := range LogFormats() {
+		if strings.EqualFold(string(f), s) {
+			return f, nil
+		}
+	}
+	return "", unknownEnumError("log format", s, enumStrings(LogFormats()))
+}
+
+func (f LogFormat) String() string {
+	return string(f)
+}
+
+func (f LogFormat) MarshalText() ([]byte, error) {
+	return []byte(f), nil
+}
+
+// UnmarshalText accepts an empty format, which means the default of the
+// enclosing section.
+func (f *LogFormat) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*f = ""
+		return nil
+	}
+	v, err := ParseLogFormat(string(text))
+	if err != nil {
+		return err
+	}
+	*f = v
+	return nil
+}
+
+func ParseLogSinkType(s string) (LogSinkType, error) {
+	for _, t := range LogSinkTypes() {
+		if strings.EqualFold(string(t), s) {
+			return t, nil
+		}
+	}
+	return "", unknownEnumError("log sink type", s, enumStrings(LogSinkTypes()))
+}
+
+func (t LogSinkType) String() string {
+	return string(t)
+}
+
+func (t LogSinkType) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+func (t *LogSinkType) UnmarshalText(text []byte) error {
+	v, err := ParseLogSinkType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
@@ -0,0 +1,121 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/case-management-suite/common/config"
+)
+
+func TestForEnvProd(t *testing.T) {
+	appConfig, err := config.ForEnv(config.Env.Prod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.Env != config.Env.Prod {
+		t.Errorf("Env = %q", appConfig.Env)
+	}
+	if appConfig.CasesStorage.DatabaseType != config.Postgres {
+		t.Errorf("DatabaseType = %d, want Postgres", appConfig.CasesStorage.DatabaseType)
+	}
+	if appConfig.RulesServiceConfig.QueueType != config.RabbitMQ {
+		t.Errorf("QueueType = %q, want RABBIT_MQ", appConfig.RulesServiceConfig.QueueType)
+	}
+	if appConfig.CasesService.Port != 7777 {
+		t.Errorf("CasesService.Port = %d, want the inherited 7777", appConfig.CasesService.Port)
+	}
+	if err := appConfig.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestForEnvTest(t *testing.T) {
+	appConfig, err := config.ForEnv(config.Env.Test)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue := appConfig.RulesServiceConfig.QueueConfig
+	if appConfig.RulesServiceConfig.QueueType != config.GoChannels || !queue.PurgeOnStart || queue.SendRetries != 2 {
+		t.Errorf("unexpected test profile: %+v", appConfig.RulesServiceConfig)
+	}
+}
+
+// registerProfile registers p for the duration of the test.
+func registerProfile(t *testing.T, p config.Profile) error {
+	t.Helper()
+	if err := config.RegisterProfile(p); err != nil {
+		return err
+	}
+	t.Cleanup(func() { config.UnregisterProfile(p.Env) })
+	return nil
+}
+
+func TestRegisterCustomProfile(t *testing.T) {
+	staging := config.EnvType("staging-profile-test")
+	err := registerProfile(t, config.Profile{
+		Env:      staging,
+		Inherits: config.Env.Prod,
+		Apply: func(c *config.AppConfig) {
+			c.CasesService.Host = "cases.staging.internal"
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registerProfile(t, config.Profile{Env: staging}); err == nil {
+		t.Error("expected duplicate registration to fail")
+	}
+
+	appConfig, err := config.ForEnv(staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.CasesService.Host != "cases.staging.internal" || appConfig.CasesStorage.DatabaseType != config.Postgres {
+		t.Errorf("unexpected staging config: %+v", appConfig)
+	}
+
+	found := false
+	for _, env := range config.EnvTypes() {
+		found = found || env == staging
+	}
+	if !found {
+		t.Errorf("EnvTypes() does not list %s", staging)
+	}
+}
+
+func TestForEnvErrors(t *testing.T) {
+	if _, err := config.ForEnv("nowhere"); err == nil {
+		t.Error("expected unknown environment to fail")
+	}
+
+	orphan := config.EnvType("orphan-profile-test")
+	if err := registerProfile(t, config.Profile{Env: orphan, Inherits: "missing-parent"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.ForEnv(orphan); err == nil {
+		t.Error("expected unknown parent to fail")
+	}
+
+	a, b := config.EnvType("cycle-a-profile-test"), config.EnvType("cycle-b-profile-test")
+	if err := registerProfile(t, config.Profile{Env: a, Inherits: b}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registerProfile(t, config.Profile{Env: b, Inherits: a}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.ForEnv(a); err == nil {
+		t.Error("expected inheritance cycle to fail")
+	}
+}
+
+func TestLoadSelectsProfileFromEnv(t *testing.T) {
+	appConfig, sources, err := config.Load(config.WithEnviron([]string{"CMS_ENV=prod"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.Env != config.Env.Prod || appConfig.CasesStorage.DatabaseType != config.Postgres {
+		t.Errorf("expected the prod profile, got %+v", appConfig)
+	}
+	if got := sources.Of("Env"); got.Kind != config.SourceEnv {
+		t.Errorf("Env source = %v", got)
+	}
+}
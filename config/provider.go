@@ -0,0 +1,281 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Provider is a key-value source of configuration, such as Consul or etcd.
+// Keys are field paths separated by slashes or dots and matched like the keys
+// of a file, e.g. "casesService/port" or "databases.cases-replica.address".
+type Provider interface {
+	// Name identifies the provider in the Sources, e.g. "consul".
+	Name() string
+	Get(ctx context.Context, key string) (KeyValue, bool, error)
+	List(ctx context.Context, prefix string) ([]KeyValue, error)
+	// Watch sends the keys that change under prefix until ctx is done, then
+	// closes the channel.
+	Watch(ctx context.Context, prefix string) (<-chan string, error)
+}
+
+// KeyValue is a value of a Provider with its provenance.
+type KeyValue struct {
+	Key   string
+	Value string
+	// Origin locates the value in the backend, e.g. the file it was read from.
+	Origin string
+}
+
+type providerSource struct {
+	provider Provider
+	prefix   string
+}
+
+// WithProvider reads the keys under prefix from p, after the file and before
+// the environment variables. Providers apply in the order they are given, a
+// later provider overriding the keys of the previous ones.
+func WithProvider(p Provider, prefix string) LoadOption {
+	return func(l *loader) {
+		l.providers = append(l.providers, providerSource{provider: p, prefix: prefix})
+	}
+}
+
+// WithContext sets the context of the provider requests.
+func WithContext(ctx context.Context) LoadOption {
+	return func(l *loader) {
+		l.ctx = ctx
+	}
+}
+
+func splitKey(key string) []string {
+	return strings.FieldsFunc(key, func(r rune) bool { return r == '/' || r == '.' })
+}
+
+func applyProvider(ctx context.Context, root reflect.Value, ps providerSource, sources Sources) error {
+	kvs, err := ps.provider.List(ctx, ps.prefix)
+	if err != nil {
+		return fmt.Errorf("provider %s: %w", ps.provider.Name(), err)
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	for _, kv := range kvs {
+		path := splitKey(strings.TrimPrefix(kv.Key, ps.prefix))
+		if len(path) == 0 {
+			continue
+		}
+		tree := map[string]interface{}{path[len(path)-1]: kv.Value}
+		for i := len(path) - 2; i >= 0; i-- {
+			tree = map[string]interface{}{path[i]: tree}
+		}
+		origin := kv.Origin
+		if origin == "" {
+			origin = kv.Key
+		}
+		src := Source{Kind: SourceProvider, Name: ps.provider.Name() + ":" + origin}
+		if err := applyTree(root, nil, tree, src, sources); err != nil {
+			return fmt.Errorf("provider %s: key %s: %w", ps.provider.Name(), kv.Key, err)
+		}
+	}
+	return nil
+}
+
+// DirProvider reads a value per file under Dir, keyed by the path of the file
+// relative to Dir, such as a mounted Kubernetes ConfigMap with keys like
+// "casesService.port". Hidden files and directories are skipped.
+type DirProvider struct {
+	Dir string
+}
+
+func (p DirProvider) Name() string {
+	return "dir"
+}
+
+func (p DirProvider) Get(_ context.Context, key string) (KeyValue, bool, error) {
+	path := filepath.Join(p.Dir, filepath.FromSlash(key))
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return KeyValue{}, false, nil
+	}
+	if err != nil {
+		return KeyValue{}, false, err
+	}
+	return KeyValue{Key: key, Value: strings.TrimSuffix(string(data), "\n"), Origin: path}, true, nil
+}
+
+func (p DirProvider) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	var kvs []KeyValue
+	err := filepath.WalkDir(p.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != p.Dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(p.Dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		kv, ok, err := p.Get(ctx, key)
+		if ok {
+			kvs = append(kvs, kv)
+		}
+		return err
+	})
+	return kvs, err
+}
+
+// Watch reports every change in Dir, including the hidden entries a
+// Kubernetes volume swaps on update, keyed by their relative path.
+func (p DirProvider) Watch(ctx context.Context, prefix string) (<-chan string, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(p.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			err = fw.Add(path)
+		}
+		return err
+	})
+	if err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	keys := make(chan string, 16)
+	go func() {
+		defer close(keys)
+		defer fw.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-fw.Events:
+				if !ok {
+					return
+				}
+				if ev.Op&fsnotify.Create != 0 {
+					if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+						_ = fw.Add(ev.Name)
+					}
+				}
+				rel, err := filepath.Rel(p.Dir, ev.Name)
+				if err != nil {
+					continue
+				}
+				if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) || strings.HasPrefix(key, ".") {
+					sendKey(keys, key)
+				}
+			case _, ok := <-fw.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return keys, nil
+}
+
+// sendKey drops the key when the receiver is behind, it reloads everything
+// anyway.
+func sendKey(keys chan<- string, key string) {
+	select {
+	case keys <- key:
+	default:
+	}
+}
+
+// MemoryProvider keeps its values in memory, for tests.
+type MemoryProvider struct {
+	mu       sync.Mutex
+	values   map[string]string
+	watchers map[chan string]string
+}
+
+func NewMemoryProvider(values map[string]string) *MemoryProvider {
+	p := &MemoryProvider{values: map[string]string{}, watchers: map[chan string]string{}}
+	for k, v := range values {
+		p.values[k] = v
+	}
+	return p
+}
+
+func (p *MemoryProvider) Name() string {
+	return "memory"
+}
+
+func (p *MemoryProvider) Get(_ context.Context, key string) (KeyValue, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v, ok := p.values[key]
+	return KeyValue{Key: key, Value: v}, ok, nil
+}
+
+func (p *MemoryProvider) List(_ context.Context, prefix string) ([]KeyValue, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var kvs []KeyValue
+	for k, v := range p.values {
+		if strings.HasPrefix(k, prefix) {
+			kvs = append(kvs, KeyValue{Key: k, Value: v})
+		}
+	}
+	return kvs, nil
+}
+
+func (p *MemoryProvider) Watch(ctx context.Context, prefix string) (<-chan string, error) {
+	keys := make(chan string, 16)
+	p.mu.Lock()
+	p.watchers[keys] = prefix
+	p.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		p.mu.Lock()
+		delete(p.watchers, keys)
+		p.mu.Unlock()
+		close(keys)
+	}()
+	return keys, nil
+}
+
+// Set stores value under key and notifies the watchers.
+func (p *MemoryProvider) Set(key, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[key] = value
+	p.notify(key)
+}
+
+func (p *MemoryProvider) Delete(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.values, key)
+	p.notify(key)
+}
+
+func (p *MemoryProvider) notify(key string) {
+	for keys, prefix := range p.watchers {
+		if strings.HasPrefix(key, prefix) {
+			sendKey(keys, key)
+		}
+	}
+}
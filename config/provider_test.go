@@ -0,0 +1,143 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/rs/zerolog"
+)
+
+func TestLoadProviders(t *testing.T) {
+	path := writeFile(t, "cms.yaml", "casesService:\n  host: file-host\n  port: 7000\n")
+	base := config.NewMemoryProvider(map[string]string{
+		"cms/prod/casesService/port":                          "9100",
+		"cms/prod/casesService/host":                          "kv-host",
+		"cms/prod/databases/cases-replica/address":            "replica.db",
+		"cms/prod/rulesServiceConfig/queueConfig/sendRetries": "8",
+		"cms/test/casesService/port":                          "1",
+	})
+	overrides := config.NewMemoryProvider(map[string]string{"casesService.port": "9200"})
+
+	appConfig, sources, err := config.Load(
+		config.WithFile(path),
+		config.WithProvider(base, "cms/prod/"),
+		config.WithProvider(overrides, ""),
+		config.WithEnviron([]string{"CMS_CASESSERVICE_HOST=env-host"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if appConfig.CasesService.Port != 9200 {
+		t.Errorf("the later provider did not win: port %d", appConfig.CasesService.Port)
+	}
+	if appConfig.CasesService.Host != "env-host" {
+		t.Errorf("env did not win over the providers: host %q", appConfig.CasesService.Host)
+	}
+	if appConfig.RulesServiceConfig.QueueConfig.SendRetries != 8 {
+		t.Errorf("SendRetries = %d, want 8", appConfig.RulesServiceConfig.QueueConfig.SendRetries)
+	}
+	if db, err := appConfig.Database("cases-replica"); err != nil || db.Address != "replica.db" {
+		t.Errorf("Database(cases-replica) = %+v, %v", db, err)
+	}
+
+	for path, want := range map[string]config.Source{
+		"CasesService.Port":               {Kind: config.SourceProvider, Name: "memory:casesService.port"},
+		"Databases.cases-replica.Address": {Kind: config.SourceProvider, Name: "memory:cms/prod/databases/cases-replica/address"},
+		"CasesService.Host":               {Kind: config.SourceEnv, Name: "CMS_CASESSERVICE_HOST"},
+	} {
+		if got := sources.Of(path); got != want {
+			t.Errorf("source of %s = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestLoadProviderUnknownKey(t *testing.T) {
+	p := config.NewMemoryProvider(map[string]string{"casesService/prot": "1"})
+	if _, _, err := config.Load(config.WithProvider(p, ""), config.WithEnviron([]string{})); err == nil {
+		t.Error("expected an unknown key to fail")
+	}
+}
+
+func TestDirProvider(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"casesService.port":            "9300\n",
+		"rulesServiceConfig/queueType": "GO_CHANNELS",
+		"..data/casesService.port":     "1",
+		".hidden":                      "x",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := config.DirProvider{Dir: dir}
+	kv, ok, err := p.Get(context.Background(), "casesService.port")
+	if err != nil || !ok || kv.Value != "9300" || kv.Origin != filepath.Join(dir, "casesService.port") {
+		t.Errorf("Get = %+v, %v, %v", kv, ok, err)
+	}
+	if _, ok, err := p.Get(context.Background(), "missing"); ok || err != nil {
+		t.Errorf("Get(missing) = %v, %v", ok, err)
+	}
+
+	appConfig, sources, err := config.Load(config.WithProvider(p, ""), config.WithEnviron([]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.CasesService.Port != 9300 || appConfig.RulesServiceConfig.QueueType != config.GoChannels {
+		t.Errorf("unexpected config %+v", appConfig)
+	}
+	want := config.Source{Kind: config.SourceProvider, Name: "dir:" + filepath.Join(dir, "rulesServiceConfig", "queueType")}
+	if got := sources.Of("RulesServiceConfig.QueueType"); got != want {
+		t.Errorf("source = %v, want %v", got, want)
+	}
+}
+
+func TestWatcherReloadsOnProviderChange(t *testing.T) {
+	p := config.NewMemoryProvider(map[string]string{"casesService/port": "9100"})
+	w, err := config.NewWatcher(zerolog.Nop(), config.WithProvider(p, ""), config.WithEnviron([]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan []config.Change, 1)
+	w.OnChange("CasesService", func(c []config.Change) {
+		select {
+		case changed <- c:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// The provider only notifies the watches registered by Run.
+	deadline := time.After(5 * time.Second)
+	for port := 9101; ; port++ {
+		p.Set("casesService/port", strconv.Itoa(port))
+		select {
+		case changes := <-changed:
+			if w.Current().CasesService.Port < 9101 || len(changes) != 1 {
+				t.Errorf("unexpected reload %+v", changes)
+			}
+			return
+		case <-time.After(200 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no reload after the provider changed")
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Patterns of the strings Load accepts for the non-string fields.
+const (
+	// durationPattern matches the strings accepted by time.ParseDuration.
+	durationPattern = `^[+-]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+)$`
+	intPattern      = `^[+-]?[0-9]+$`
+	uintPattern     = `^[0-9]+$`
+	floatPattern    = `^[+-]?(([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?|[Ii][Nn][Ff]([Ii][Nn][Ii][Tt][Yy])?|[Nn][Aa][Nn])$`
+	// levelPattern matches the numeric levels, from -128 to 127.
+	levelPattern = `^(\+?0*([0-9]|[1-9][0-9]|1[01][0-9]|12[0-7])|-0*([0-9]|[1-9][0-9]|1[01][0-9]|12[0-8]))$`
+)
+
+// boolStrings are the strings strconv.ParseBool accepts.
+var boolStrings = []string{"1", "t", "T", "TRUE", "true", "True", "0", "f", "F", "FALSE", "false", "False"}
+
+// schemaConstraints mirrors the rules of Validate that a schema can express,
+// keyed by type and field name.
+var schemaConstraints = map[string]map[string]interface{}{
+	"CasesServiceConfig.Host":                        {"minLength": 1},
+	"CasesServiceConfig.Port":                        {"minimum": minPort, "maximum": maxPort},
+	"GraphQLConfig.Port":                             {"minimum": minPort, "maximum": maxPort},
+	"DatabaseConfig.CreateSQL":                       {"deprecated": true},
+	"PostgresConfig.Port":                            {"minimum": 0, "maximum": maxPort},
+	"PostgresConfig.SSLMode":                         {"enum": append([]string{""}, postgresSSLModes...)},
+	"SqliteConfig.JournalMode":                       {"enum": append([]string{""}, sqliteJournalModes...)},
+	"MySQLConfig.Port":                               {"minimum": 0, "maximum": maxPort},
+	"PoolConfig.MaxOpenConns":                        {"minimum": 0},
+	"PoolConfig.MaxIdleConns":                        {"minimum": 0},
+	"QueueConnectionConfig.CaseActionsChannel":       {"minLength": 1},
+	"QueueConnectionConfig.CaseNotificationsChannel": {"minLength": 1},
+	"QueueConnectionConfig.SendRetries":              {"minimum": 0},
+}
+
+// JSONSchema describes the configuration files read by Load, for editors and
+// linters. The properties use the lowerCamel spelling, e.g. casesService.port,
+// and the pattern properties accept the other spellings Load matches, which
+// ignores the case, '_' and '-'. The enums are matched case-insensitively and
+// the numbers and booleans may be written as strings, like Load does. Rules
+// that span fields, and the bounds of numbers written as strings, are only
+// checked by Load.
+func JSONSchema() ([]byte, error) {
+	g := schemaGenerator{defs: map[string]interface{}{}}
+	root := g.structSchema(reflect.TypeOf(AppConfig{}))
+	root["$schema"] = schemaDialect
+	root["title"] = "AppConfig"
+	root["$defs"] = g.defs
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+func (g schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	patterns := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if skipField(sf) {
+			continue
+		}
+		s := g.typeSchema(sf.Type)
+		for k, v := range schemaConstraints[t.Name()+"."+sf.Name] {
+			mergeConstraint(s, k, v)
+		}
+		s = withStringForms(sf.Type, s)
+		if help := sf.Tag.Get(helpTag); help != "" {
+			s["description"] = help
+		}
+		if _, ok := sf.Tag.Lookup(redactTag); ok {
+			s["writeOnly"] = true
+		}
+		properties[lowerCamel(sf.Name)] = s
+		patterns[keyPattern(sf.Name)] = s
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"patternProperties":    patterns,
+		"additionalProperties": false,
+	}
+}
+
+func (g schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case reflect.TypeOf(EnvType("")):
+		return enumSchema(enumStrings(EnvTypes()), nil)
+	case reflect.TypeOf(ApiType(0)):
+		var values []int
+		for _, t := range ApiTypes() {
+			values = append(values, int(t))
+		}
+		return enumSchema(apiTypeNames, values)
+	case reflect.TypeOf(DatabaseType(0)):
+		var values []int
+		for _, t := range DatabaseTypes() {
+			values = append(values, int(t))
+		}
+		return enumSchema(databaseTypeNames(), values)
+	case reflect.TypeOf(QueueType("")):
+		return enumSchema(enumStrings(QueueTypes()), nil)
+	case reflect.TypeOf(LogFormat("")):
+		return enumSchema(append([]string{""}, enumStrings(LogFormats())...), nil)
+	case reflect.TypeOf(LogSinkType("")):
+		return enumSchema(enumStrings(LogSinkTypes()), nil)
+	case reflect.TypeOf(zerolog.Level(0)):
+		// zerolog parses the names case-sensitively, and "" as NoLevel.
+		levels := []string{""}
+		for l := zerolog.TraceLevel; l <= zerolog.Disabled; l++ {
+			levels = append(levels, l.String())
+		}
+		return map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"type": "string", "enum": levels},
+			map[string]interface{}{"type": "string", "pattern": levelPattern},
+			map[string]interface{}{"type": "integer", "minimum": -128, "maximum": 127},
+		}}
+	case reflect.TypeOf(Percentage{}):
+		return map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 100},
+			map[string]interface{}{"type": "string", "pattern": `^([+-]?[0-9]+)?$`},
+		}}
+	case durationType:
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+	}
+
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+	if isInstancesType(t) {
+		return map[string]interface{}{
+			"type":                 "object",
+			"propertyNames":        map[string]interface{}{"pattern": instanceNamePattern.String()},
+			"additionalProperties": g.typeSchema(t.Elem()),
+		}
+	}
+	if isListType(t) {
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	}
+	if t.Kind() == reflect.Struct {
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		bits := t.Bits()
+		return map[string]interface{}{"type": "integer", "minimum": -(1 << (bits - 1)), "maximum": 1<<(bits-1) - 1}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 1<<t.Bits() - 1}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// enumSchema accepts names case-insensitively and, when there are values, the
+// numbers of the enum, in the order editors offer them.
+func enumSchema(names []string, values []int) map[string]interface{} {
+	alternatives := make([]string, 0, len(names)+len(values))
+	for _, name := range names {
+		alternatives = append(alternatives, foldPattern(name))
+	}
+	for _, v := range values {
+		if v == 0 {
+			alternatives = append(alternatives, `[+-]?0+`)
+		} else {
+			alternatives = append(alternatives, `\+?0*`+strconv.Itoa(v))
+		}
+	}
+	forms := []interface{}{
+		map[string]interface{}{"type": "string", "enum": names},
+		map[string]interface{}{"type": "string", "pattern": "^(" + strings.Join(alternatives, "|") + ")$"},
+	}
+	if len(values) > 0 {
+		forms = append(forms, map[string]interface{}{"type": "integer", "enum": values})
+	}
+	return map[string]interface{}{"anyOf": forms}
+}
+
+// withStringForms adds to the schema s of a number or a boolean the strings
+// Load parses as such.
+func withStringForms(t reflect.Type, s map[string]interface{}) map[string]interface{} {
+	if t == durationType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return s
+	}
+	var forms []interface{}
+	switch t.Kind() {
+	case reflect.Bool:
+		forms = []interface{}{
+			map[string]interface{}{"type": "string", "enum": boolStrings},
+			map[string]interface{}{"type": "integer", "enum": []int{0, 1}},
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		forms = []interface{}{map[string]interface{}{"type": "string", "pattern": intPattern}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		forms = []interface{}{map[string]interface{}{"type": "string", "pattern": uintPattern}}
+	case reflect.Float32, reflect.Float64:
+		forms = []interface{}{map[string]interface{}{"type": "string", "pattern": floatPattern}}
+	default:
+		return s
+	}
+	return map[string]interface{}{"anyOf": append([]interface{}{s}, forms...)}
+}
+
+// keyPattern matches the keys Load reads into the field name, which it
+// compares lowercased, without '_' and '-'.
+func keyPattern(name string) string {
+	var b strings.Builder
+	b.WriteString("^[_-]*")
+	for _, r := range strings.ToLower(name) {
+		var class []rune
+		for _, o := range foldOrbit(r) {
+			if unicode.ToLower(o) == r {
+				class = append(class, o)
+			}
+		}
+		b.WriteString(classPattern(class))
+		b.WriteString("[_-]*")
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// foldPattern matches the strings equal to s under strings.EqualFold.
+func foldPattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		b.WriteString(classPattern(foldOrbit(r)))
+	}
+	return b.String()
+}
+
+// foldOrbit returns r and the runes equal to it under simple case folding.
+func foldOrbit(r rune) []rune {
+	runes := []rune{r}
+	for o := unicode.SimpleFold(r); o != r; o = unicode.SimpleFold(o) {
+		runes = append(runes, o)
+	}
+	return runes
+}
+
+func classPattern(runes []rune) string {
+	if len(runes) == 1 {
+		return regexp.QuoteMeta(string(runes))
+	}
+	return "[" + string(runes) + "]"
+}
+
+// mergeConstraint adds a constraint to s, keeping the stricter bound when the
+// type already has one.
+func mergeConstraint(s map[string]interface{}, key string, value interface{}) {
+	if old, ok := s[key].(int); ok {
+		if v, ok := value.(int); ok {
+			if (key == "minimum" && old > v) || (key == "maximum" && old < v) {
+				return
+			}
+		}
+	}
+	s[key] = value
+}
+
+// lowerCamel lowercases the leading word or acronym of a field name: "APIType"
+// becomes "apiType" and "CasesService" becomes "casesService".
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
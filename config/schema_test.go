@@ -0,0 +1,222 @@
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/case-management-suite/common/config"
+)
+
+type schemaNode struct {
+	Type                 string                `json:"type"`
+	Ref                  string                `json:"$ref"`
+	Enum                 []interface{}         `json:"enum"`
+	AnyOf                []schemaNode          `json:"anyOf"`
+	Minimum              *int                  `json:"minimum"`
+	Maximum              *int                  `json:"maximum"`
+	Description          string                `json:"description"`
+	Properties           map[string]schemaNode `json:"properties"`
+	AdditionalProperties interface{}           `json:"additionalProperties"`
+	Defs                 map[string]schemaNode `json:"$defs"`
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := config.JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root schemaNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	if root.Type != "object" || root.AdditionalProperties != false {
+		t.Errorf("root must be a closed object: %+v", root)
+	}
+	if ref := root.Properties["rulesServiceConfig"].Ref; ref != "#/$defs/RulesServiceConfig" {
+		t.Errorf("rulesServiceConfig $ref = %q", ref)
+	}
+	databases := root.Properties["databases"]
+	if entry, ok := databases.AdditionalProperties.(map[string]interface{}); databases.Type != "object" || !ok || entry["$ref"] != "#/$defs/DatabaseConfig" {
+		t.Errorf("databases = %+v", databases)
+	}
+
+	rules := root.Defs["RulesServiceConfig"]
+	if enum := rules.Properties["queueType"].AnyOf[0].Enum; len(enum) != 2 || enum[0] != "RABBIT_MQ" || enum[1] != "GO_CHANNELS" {
+		t.Errorf("queueType enum = %v", enum)
+	}
+	if _, ok := rules.Properties["logger"]; ok {
+		t.Error("the Logger field must not be in the schema")
+	}
+	if ref := rules.Properties["queueConfig"].Ref; ref != "#/$defs/QueueConnectionConfig" {
+		t.Errorf("queueConfig $ref = %q", ref)
+	}
+	queue := root.Defs["QueueConnectionConfig"]
+	if retries := queue.Properties["sendRetries"].AnyOf[0]; retries.Type != "integer" || retries.Minimum == nil || *retries.Minimum != 0 {
+		t.Errorf("sendRetries = %+v", retries)
+	}
+	if level := queue.Properties["logLevel"].AnyOf[0]; len(level.Enum) < 2 || level.Enum[1] != "trace" {
+		t.Errorf("logLevel = %+v", level)
+	}
+
+	if enum := root.Defs["APIConfig"].Properties["apiType"].AnyOf[0].Enum; len(enum) != 2 || enum[1] != "GraphQL" {
+		t.Errorf("apiType enum = %v", enum)
+	}
+	if enum := root.Defs["DatabaseConfig"].Properties["databaseType"].AnyOf[0].Enum; len(enum) != 3 {
+		t.Errorf("databaseType enum = %v", enum)
+	}
+
+	port := root.Defs["GraphQLConfig"].Properties["port"].AnyOf[0]
+	if port.Minimum == nil || *port.Minimum != 1 || port.Maximum == nil || *port.Maximum != 65535 {
+		t.Errorf("graphQLConfig.port = %+v", port)
+	}
+	if desc := root.Defs["GraphQLConfig"].Properties["port"].Description; desc != "Port the GraphQL server listens on" {
+		t.Errorf("graphQLConfig.port description = %q", desc)
+	}
+	// CasesServiceConfig.Port is an int16, narrower than the port range.
+	if max := root.Defs["CasesServiceConfig"].Properties["port"].AnyOf[0].Maximum; max == nil || *max != 32767 {
+		t.Errorf("casesService.port maximum = %v", max)
+	}
+}
+
+// TestJSONSchemaAcceptsWhatLoadAccepts validates documents against the schema
+// with the keywords it uses, and loads them.
+func TestJSONSchemaAcceptsWhatLoadAccepts(t *testing.T) {
+	data, err := config.JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		doc     string
+		invalid bool
+	}{
+		{name: "PascalCase", doc: `{"CasesService": {"Port": 9000}, "RulesServiceConfig": {"QueueConfig": {"SendRetries": 7, "LogLevel": "warn"}}}`},
+		{name: "lowerCamel", doc: `{"casesService": {"port": 9000}}`},
+		{name: "SnakeAndKebab", doc: `{"cases_service": {"port": 9000}, "rules-service-config": {"queue_type": "GO_CHANNELS"}}`},
+		{name: "EnumCase", doc: `{"rulesServiceConfig": {"queueType": "go_channels"}, "api": {"apiType": "graphql"}, "casesStorage": {"databaseType": "SQLITE"}}`},
+		{name: "EnumNumbers", doc: `{"api": {"apiType": 1}, "casesStorage": {"databaseType": "0"}}`},
+		{name: "StringScalars", doc: `{"casesService": {"port": "9000"}, "rulesServiceConfig": {"queueConfig": {"purgeOnStart": "TRUE", "logLevel": "1"}}}`},
+		{name: "Duration", doc: `{"casesStorage": {"pool": {"connMaxLifetime": "1.5h"}}}`},
+		{name: "FeatureRules", doc: `{"features": {"workflow": {"rollout": 0, "rules": [{"name": "prod", "env": "PROD", "value": "v2", "rollout": "50"}]}}}`},
+		{name: "UnknownKey", doc: `{"casesServices": {}}`, invalid: true},
+		{name: "UnknownEnum", doc: `{"api": {"apiType": "soap"}}`, invalid: true},
+		{name: "LevelCase", doc: `{"log": {"level": "WARN"}}`, invalid: true},
+		{name: "EnumOutOfRange", doc: `{"api": {"apiType": 2}}`, invalid: true},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			var doc interface{}
+			if err := json.Unmarshal([]byte(v.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			schemaErr := validateSchema(schema, schema, doc)
+			_, _, loadErr := config.Load(config.WithFile(writeFile(t, "cms.json", v.doc)), config.WithEnviron([]string{}))
+			if (schemaErr != nil) != v.invalid || (loadErr != nil) != v.invalid {
+				t.Errorf("schema: %v, Load: %v, want invalid %v", schemaErr, loadErr, v.invalid)
+			}
+		})
+	}
+}
+
+func validateSchema(root, s map[string]interface{}, v interface{}) error {
+	if ref, ok := s["$ref"].(string); ok {
+		def := root["$defs"].(map[string]interface{})[strings.TrimPrefix(ref, "#/$defs/")]
+		return validateSchema(root, def.(map[string]interface{}), v)
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		var errs []string
+		for _, sub := range anyOf {
+			err := validateSchema(root, sub.(map[string]interface{}), v)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("no alternative matches: %s", strings.Join(errs, "; "))
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == v
+		}
+		if !found {
+			return fmt.Errorf("%v is not one of %v", v, enum)
+		}
+	}
+	switch s["type"] {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not an object", v)
+		}
+		for key, value := range obj {
+			var subs []interface{}
+			properties, _ := s["properties"].(map[string]interface{})
+			if p, ok := properties[key]; ok {
+				subs = append(subs, p)
+			}
+			patterns, _ := s["patternProperties"].(map[string]interface{})
+			for pattern, p := range patterns {
+				if regexp.MustCompile(pattern).MatchString(key) {
+					subs = append(subs, p)
+				}
+			}
+			if len(subs) == 0 {
+				if s["additionalProperties"] == false {
+					return fmt.Errorf("unknown key %q", key)
+				}
+				if p, ok := s["additionalProperties"].(map[string]interface{}); ok {
+					subs = append(subs, p)
+				}
+			}
+			for _, sub := range subs {
+				if err := validateSchema(root, sub.(map[string]interface{}), value); err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", v)
+		}
+		if pattern, ok := s["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(str) {
+			return fmt.Errorf("%q does not match %s", str, pattern)
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok || (s["type"] == "integer" && n != float64(int64(n))) {
+			return fmt.Errorf("%v is not an %s", v, s["type"])
+		}
+		if min, ok := s["minimum"].(float64); ok && n < min {
+			return fmt.Errorf("%v is below %v", n, min)
+		}
+		if max, ok := s["maximum"].(float64); ok && n > max {
+			return fmt.Errorf("%v is above %v", n, max)
+		}
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not an array", v)
+		}
+		for i, item := range list {
+			if err := validateSchema(root, s["items"].(map[string]interface{}), item); err != nil {
+				return fmt.Errorf("%d: %w", i, err)
+			}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%v is not a boolean", v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// SecretProvider resolves the references of its scheme, e.g. "/run/secrets/pg"
+// in ${file:/run/secrets/pg} or "PG_PASSWORD" in ${env:PG_PASSWORD}.
+type SecretProvider interface {
+	Scheme() string
+	Resolve(ref string) (string, error)
+}
+
+// FileSecretProvider reads secrets from files, such as Docker or Kubernetes
+// mounted secrets. A single trailing newline is dropped.
+type FileSecretProvider struct{}
+
+func (FileSecretProvider) Scheme() string {
+	return "file"
+}
+
+func (FileSecretProvider) Resolve(ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(s, "\r"), nil
+}
+
+// EnvSecretProvider reads secrets from environment variables.
+type EnvSecretProvider struct {
+	LookupEnv func(string) (string, bool)
+}
+
+func (EnvSecretProvider) Scheme() string {
+	return "env"
+}
+
+func (p EnvSecretProvider) Resolve(ref string) (string, error) {
+	lookup := p.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	value, ok := lookup(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+}
+
+var secretRefPattern = regexp.MustCompile(`\$\{([a-zA-Z][a-zA-Z0-9_-]*):([^}]*)\}`)
+
+// WithSecretProvider registers p for its scheme, replacing any provider
+// previously registered for it. The file and env schemes are available by default.
+func WithSecretProvider(p SecretProvider) LoadOption {
+	return func(l *loader) {
+		if l.secrets == nil {
+			l.secrets = map[string]SecretProvider{}
+		}
+		l.secrets[p.Scheme()] = p
+	}
+}
+
+func resolveSecrets(root reflect.Value, providers map[string]SecretProvider) error {
+	return walkLeaves(root, nil, func(f leaf) error {
+		if f.Value.Kind() != reflect.String || !strings.Contains(f.Value.String(), "${") {
+			return nil
+		}
+		var resolveErr error
+		resolved := secretRefPattern.ReplaceAllStringFunc(f.Value.String(), func(ref string) string {
+			m := secretRefPattern.FindStringSubmatch(ref)
+			p, ok := providers[m[1]]
+			if !ok {
+				resolveErr = fmt.Errorf("%s: unknown secret provider %q", f.key(), m[1])
+				return ref
+			}
+			value, err := p.Resolve(m[2])
+			if err != nil && resolveErr == nil {
+				resolveErr = fmt.Errorf("%s: resolving secret %s: %w", f.key(), ref, err)
+			}
+			return value
+		})
+		if resolveErr != nil {
+			return resolveErr
+		}
+		f.Value.SetString(resolved)
+		return nil
+	})
+}
+
+// redactedValue matches what url.URL.Redacted uses for passwords.
+const redactedValue = "xxxxx"
+
+// Fields tagged with `redact:"url"`, `redact:"dsn"` or `redact:"full"` hold
+// credentials and are masked whenever the configuration is printed or logged.
+// The dsn fields of a DatabaseConfig are masked by the driver of its DatabaseType.
+const redactTag = "redact"
+
+var dsnPasswordPattern = regexp.MustCompile(`(?i)(password\s*=\s*)('[^']*'|\S+)`)
+
+func redactString(mode, s string) string {
+	if s == "" {
+		return s
+	}
+	switch mode {
+	case "url":
+		return redactURL(s)
+	case "dsn":
+		if strings.Contains(s, "://") {
+			return redactURL(s)
+		}
+		return dsnPasswordPattern.ReplaceAllString(s, "${1}"+redactedValue)
+	default:
+		return redactedValue
+	}
+}
+
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.Redacted()
+}
+
+func redactFields(v reflect.Value) {
+	_ = walkLeaves(v, nil, func(f leaf) error {
+		mode, ok := f.Field.Tag.Lookup(redactTag)
+		if !ok || f.Value.Kind() != reflect.String {
+			return nil
+		}
+		if c, isDatabase := f.Parent.Interface().(DatabaseConfig); isDatabase && mode == "dsn" {
+			f.Value.SetString(c.redactAddress(f.Value.String()))
+			return nil
+		}
+		f.Value.SetString(redactString(mode, f.Value.String()))
+		return nil
+	})
+}
+
+func redacted[T any](v T) T {
+	redactFields(reflect.ValueOf(&v).Elem())
+	return v
+}
+
+// Redacted returns a copy of the configuration with its credentials masked.
+func (c AppConfig) Redacted() AppConfig {
+	return redacted(c)
+}
+
+func (c DatabaseConfig) String() string {
+	type plain DatabaseConfig
+	return fmt.Sprintf("%+v", plain(redacted(c)))
+}
+
+func (c DatabaseConfig) MarshalJSON() ([]byte, error) {
+	type plain DatabaseConfig
+	return json.Marshal(plain(redacted(c)))
+}
+
+func (c DatabaseConfig) MarshalYAML() (interface{}, error) {
+	type plain DatabaseConfig
+	return plain(redacted(c)), nil
+}
+
+func (c QueueConnectionConfig) String() string {
+	type plain QueueConnectionConfig
+	return fmt.Sprintf("%+v", plain(redacted(c)))
+}
+
+func (c QueueConnectionConfig) MarshalJSON() ([]byte, error) {
+	type plain QueueConnectionConfig
+	return json.Marshal(plain(redacted(c)))
+}
+
+func (c QueueConnectionConfig) MarshalYAML() (interface{}, error) {
+	type plain QueueConnectionConfig
+	return plain(redacted(c)), nil
+}
+
+func (c LogConfig) String() string {
+	type plain LogConfig
+	return fmt.Sprintf("%+v", plain(redacted(c)))
+}
+
+func (c LogConfig) MarshalJSON() ([]byte, error) {
+	type plain LogConfig
+	return json.Marshal(plain(redacted(c)))
+}
+
+func (c LogConfig) MarshalYAML() (interface{}, error) {
+	type plain LogConfig
+	return plain(redacted(c)), nil
+}
+
+func (c PostgresConfig) String() string {
+	type plain PostgresConfig
+	return fmt.Sprintf("%+v", plain(redacted(c)))
+}
+
+func (c PostgresConfig) MarshalJSON() ([]byte, error) {
+	type plain PostgresConfig
+	return json.Marshal(plain(redacted(c)))
+}
+
+func (c PostgresConfig) MarshalYAML() (interface{}, error) {
+	type plain PostgresConfig
+	return plain(redacted(c)), nil
+}
+
+func (c MySQLConfig) String() string {
+	type plain MySQLConfig
+	return fmt.Sprintf("%+v", plain(redacted(c)))
+}
+
+func (c MySQLConfig) MarshalJSON() ([]byte, error) {
+	type plain MySQLConfig
+	return json.Marshal(plain(redacted(c)))
+}
+
+func (c MySQLConfig) MarshalYAML() (interface{}, error) {
+	type plain MySQLConfig
+	return plain(redacted(c)), nil
+}
@@ -0,0 +1,401 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	minPort = 1
+	maxPort = 65535
+)
+
+// FieldError is a single rule violation, located by its dotted field path.
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors collects every violation found in a configuration.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("invalid configuration (%d errors): %s", len(e), strings.Join(msgs, "; "))
+}
+
+type validation struct {
+	errs ValidationErrors
+}
+
+func (v *validation) addf(path string, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func fieldPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// Validate checks every section of the configuration and reports all the
+// problems at once as ValidationErrors.
+func (c AppConfig) Validate() error {
+	v := &validation{}
+	if c.Env == "" {
+		v.addf("Env", "must not be empty")
+	}
+	c.API.validate(v, "API")
+	c.CasesService.validate(v, "CasesService")
+	c.CasesStorage.validate(v, "CasesStorage")
+	c.GraphQLConfig.validate(v, "GraphQLConfig")
+	c.RulesServiceConfig.validate(v, "RulesServiceConfig")
+	c.Log.validate(v, "Log")
+	for _, name := range sortedKeys(c.Databases) {
+		path := fieldPath("Databases", name)
+		validateInstanceName(v, path, name)
+		c.Databases[name].validate(v, path)
+	}
+	for _, name := range sortedKeys(c.Queues) {
+		path := fieldPath("Queues", name)
+		validateInstanceName(v, path, name)
+		c.Queues[name].validate(v, path, c.RulesServiceConfig.QueueType)
+	}
+	for _, name := range sortedKeys(c.Features) {
+		path := fieldPath("Features", name)
+		validateInstanceName(v, path, name)
+		c.Features[name].validate(v, path)
+	}
+	c.validateRefs(v)
+	return v.err()
+}
+
+func (c FeatureConfig) validate(v *validation, path string) {
+	validateRollout(v, fieldPath(path, "Rollout"), c.Rollout)
+	names := map[string]bool{}
+	for i, rule := range c.Rules {
+		rulePath := fieldPath(fieldPath(path, "Rules"), strconv.Itoa(i))
+		validateInstanceName(v, fieldPath(rulePath, "Name"), rule.Name)
+		if names[rule.Name] {
+			v.addf(fieldPath(rulePath, "Name"), "duplicate rule name %q", rule.Name)
+		}
+		names[rule.Name] = true
+		validateRollout(v, fieldPath(rulePath, "Rollout"), rule.Rollout)
+	}
+}
+
+func validateRollout(v *validation, path string, rollout Percentage) {
+	if percent, ok := rollout.Get(); ok && (percent < 0 || percent > 100) {
+		v.addf(path, "must be between 0 and 100, got %d", percent)
+	}
+}
+
+func validateInstanceName(v *validation, path, name string) {
+	if !instanceNamePattern.MatchString(name) {
+		v.addf(path, "invalid name %q, use lowercase letters, digits and dashes", name)
+	}
+}
+
+// validateRefs checks that the references to Databases and Queues resolve.
+func (c AppConfig) validateRefs(v *validation) {
+	if ref := c.CasesService.ReportingDatabase; ref != "" {
+		if _, err := c.Database(ref); err != nil {
+			v.addf("CasesService.ReportingDatabase", "%v", err)
+		}
+	}
+	if ref := c.RulesServiceConfig.AuditQueue; ref != "" {
+		if _, err := c.Queue(ref); err != nil {
+			v.addf("RulesServiceConfig.AuditQueue", "%v", err)
+		}
+	}
+}
+
+func validatePort(v *validation, path string, port int) {
+	if port < minPort || port > maxPort {
+		v.addf(path, "port %d is out of range [%d, %d]", port, minPort, maxPort)
+	}
+}
+
+func (c APIConfig) validate(v *validation, path string) {
+	if !isKnown(c.APIType, ApiTypes()) {
+		v.addf(fieldPath(path, "APIType"), "unknown API type %v", c.APIType)
+	}
+}
+
+func (c CasesServiceConfig) validate(v *validation, path string) {
+	if c.Host == "" {
+		v.addf(fieldPath(path, "Host"), "must not be empty")
+	}
+	validatePort(v, fieldPath(path, "Port"), int(c.Port))
+}
+
+func (c GraphQLConfig) validate(v *validation, path string) {
+	validatePort(v, fieldPath(path, "Port"), c.Port)
+}
+
+func (c DatabaseConfig) validate(v *validation, path string) {
+	d, err := lookupDatabaseDriver(c.DatabaseType)
+	if err != nil {
+		v.addf(fieldPath(path, "DatabaseType"), "%v", err)
+		return
+	}
+	addrPath := fieldPath(path, "Address")
+	if c.Address == "" && !d.Configured(c) {
+		v.addf(addrPath, "must not be empty unless the %s section is set", d.Name())
+		return
+	}
+	if c.DatabaseType == Sqlite && isPostgresAddress(c.Address) {
+		v.addf(addrPath, "looks like a Postgres connection string but the database type is Sqlite")
+		return
+	}
+	n, err := c.Normalized()
+	if err != nil {
+		v.addf(addrPath, "invalid connection string: %v", err)
+		return
+	}
+	switch c.DatabaseType {
+	case Sqlite:
+		n.Sqlite.validate(v, fieldPath(path, "Sqlite"))
+	case Postgres:
+		n.Postgres.validate(v, fieldPath(path, "Postgres"))
+	case MySQL:
+		n.MySQL.validate(v, fieldPath(path, "MySQL"))
+	}
+	c.Pool.validate(v, fieldPath(path, "Pool"))
+}
+
+var (
+	postgresSSLModes   = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	sqliteJournalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+)
+
+func oneOf(value string, values []string, fold bool) bool {
+	for _, v := range values {
+		if v == value || (fold && strings.EqualFold(v, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c PostgresConfig) validate(v *validation, path string) {
+	if c.Host == "" {
+		v.addf(fieldPath(path, "Host"), "must not be empty")
+	}
+	if c.Port != 0 {
+		validatePort(v, fieldPath(path, "Port"), c.Port)
+	}
+	if c.DBName == "" {
+		v.addf(fieldPath(path, "DBName"), "must not be empty")
+	}
+	if c.SSLMode != "" && !oneOf(c.SSLMode, postgresSSLModes, false) {
+		v.addf(fieldPath(path, "SSLMode"), "unknown sslmode %q, expected one of %s", c.SSLMode, strings.Join(postgresSSLModes, ", "))
+	}
+}
+
+func (c SqliteConfig) validate(v *validation, path string) {
+	if c.Path == "" {
+		v.addf(fieldPath(path, "Path"), "must not be empty")
+	}
+	if c.JournalMode != "" && !oneOf(c.JournalMode, sqliteJournalModes, true) {
+		v.addf(fieldPath(path, "JournalMode"), "unknown journal mode %q, expected one of %s", c.JournalMode, strings.Join(sqliteJournalModes, ", "))
+	}
+	if c.BusyTimeout < 0 {
+		v.addf(fieldPath(path, "BusyTimeout"), "must be >= 0, got %s", c.BusyTimeout)
+	}
+}
+
+func (c MySQLConfig) validate(v *validation, path string) {
+	if c.Host == "" {
+		v.addf(fieldPath(path, "Host"), "must not be empty")
+	}
+	if c.Port != 0 {
+		validatePort(v, fieldPath(path, "Port"), c.Port)
+	}
+	if c.DBName == "" {
+		v.addf(fieldPath(path, "DBName"), "must not be empty")
+	}
+}
+
+func (c PoolConfig) validate(v *validation, path string) {
+	if c.MaxOpenConns < 0 {
+		v.addf(fieldPath(path, "MaxOpenConns"), "must be >= 0, got %d", c.MaxOpenConns)
+	}
+	if c.MaxIdleConns < 0 {
+		v.addf(fieldPath(path, "MaxIdleConns"), "must be >= 0, got %d", c.MaxIdleConns)
+	} else if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		v.addf(fieldPath(path, "MaxIdleConns"), "must not exceed MaxOpenConns (%d)", c.MaxOpenConns)
+	}
+	if c.ConnMaxLifetime < 0 {
+		v.addf(fieldPath(path, "ConnMaxLifetime"), "must be >= 0, got %s", c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime < 0 {
+		v.addf(fieldPath(path, "ConnMaxIdleTime"), "must be >= 0, got %s", c.ConnMaxIdleTime)
+	}
+}
+
+func isPostgresAddress(address string) bool {
+	if strings.HasPrefix(address, "postgres://") || strings.HasPrefix(address, "postgresql://") {
+		return true
+	}
+	if ext := filepath.Ext(address); ext == ".db" || ext == ".sqlite" || ext == ".sqlite3" {
+		return false
+	}
+	return strings.Contains(address, "=")
+}
+
+func (c RulesServiceConfig) validate(v *validation, path string) {
+	if !isKnown(c.QueueType, QueueTypes()) {
+		v.addf(fieldPath(path, "QueueType"), "unknown queue type %q", c.QueueType)
+	}
+	c.QueueConfig.validate(v, fieldPath(path, "QueueConfig"), c.QueueType)
+}
+
+func (c QueueConnectionConfig) validate(v *validation, path string, queueType QueueType) {
+	if queueType == RabbitMQ {
+		validateAMQPAddress(v, fieldPath(path, "Address"), c.Address)
+	}
+	if c.CaseActionsChannel == "" {
+		v.addf(fieldPath(path, "CaseActionsChannel"), "must not be empty")
+	}
+	if c.CaseNotificationsChannel == "" {
+		v.addf(fieldPath(path, "CaseNotificationsChannel"), "must not be empty")
+	}
+	if c.SendRetries < 0 {
+		v.addf(fieldPath(path, "SendRetries"), "must be >= 0, got %d", c.SendRetries)
+	}
+	validateLogLevel(v, fieldPath(path, "LogLevel"), c.LogLevel)
+}
+
+func (c LogConfig) validate(v *validation, path string) {
+	if !isKnown(c.Format, LogFormats()) {
+		v.addf(fieldPath(path, "Format"), "unknown log format %q", c.Format)
+	}
+	validateLogLevel(v, fieldPath(path, "Level"), c.Level)
+	for _, name := range sortedKeys(c.Sinks) {
+		sinkPath := fieldPath(fieldPath(path, "Sinks"), name)
+		validateInstanceName(v, sinkPath, name)
+		c.Sinks[name].validate(v, sinkPath)
+	}
+	for _, name := range sortedKeys(c.Redact) {
+		rulePath := fieldPath(fieldPath(path, "Redact"), name)
+		validateInstanceName(v, rulePath, name)
+		c.Redact[name].validate(v, rulePath)
+		if c.Redact[name].Hash && c.RedactKey == "" {
+			v.addf(fieldPath(rulePath, "Hash"), "requires a RedactKey")
+		}
+	}
+	patterns := map[string]string{}
+	for _, name := range sortedKeys(c.Components) {
+		componentPath := fieldPath(fieldPath(path, "Components"), name)
+		validateInstanceName(v, componentPath, name)
+		component := c.Components[name]
+		if !validComponentPattern(component.Pattern) {
+			v.addf(fieldPath(componentPath, "Pattern"), "invalid component pattern %q", component.Pattern)
+		} else if other, ok := patterns[component.Pattern]; ok {
+			v.addf(fieldPath(componentPath, "Pattern"), "pattern %q is already leveled by %s", component.Pattern, other)
+		} else {
+			patterns[component.Pattern] = name
+		}
+		validateLogLevel(v, fieldPath(componentPath, "Level"), component.Level)
+	}
+}
+
+// validComponentPattern tells whether pattern is a component name, a name
+// ending in .* or *, as accepted by the level registry of the logger package.
+func validComponentPattern(pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+	name := strings.TrimSuffix(pattern, ".*")
+	return name != "" && !strings.Contains(name, "*") && !strings.Contains(name, "..") && !strings.HasPrefix(name, ".") && !strings.HasSuffix(name, ".")
+}
+
+func (c LogRedactRule) validate(v *validation, path string) {
+	if (c.Key == "") == (c.Pattern == "") {
+		v.addf(path, "exactly one of Key and Pattern must be set")
+	}
+	if c.Pattern != "" {
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			v.addf(fieldPath(path, "Pattern"), "invalid regular expression: %v", err)
+		}
+	}
+}
+
+func (c LogSinkConfig) validate(v *validation, path string) {
+	if c.Format != "" && !isKnown(c.Format, LogFormats()) {
+		v.addf(fieldPath(path, "Format"), "unknown log format %q", c.Format)
+	}
+	validateLogLevel(v, fieldPath(path, "Level"), c.Level)
+	switch c.Type {
+	case SinkStderr, SinkStdout:
+	case SinkFile:
+		if c.Path == "" {
+			v.addf(fieldPath(path, "Path"), "must not be empty for a file sink")
+		}
+		if c.MaxSizeMB < 0 {
+			v.addf(fieldPath(path, "MaxSizeMB"), "must be >= 0, got %d", c.MaxSizeMB)
+		}
+		if c.RotateEvery < 0 {
+			v.addf(fieldPath(path, "RotateEvery"), "must be >= 0, got %s", c.RotateEvery)
+		}
+		if c.MaxBackups < 0 {
+			v.addf(fieldPath(path, "MaxBackups"), "must be >= 0, got %d", c.MaxBackups)
+		}
+		if c.MaxAge < 0 {
+			v.addf(fieldPath(path, "MaxAge"), "must be >= 0, got %s", c.MaxAge)
+		}
+	case SinkUDP:
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			v.addf(fieldPath(path, "Address"), "invalid host:port %q", c.Address)
+		}
+	default:
+		v.addf(fieldPath(path, "Type"), "unknown log sink type %q", c.Type)
+	}
+}
+
+func validateLogLevel(v *validation, path string, level zerolog.Level) {
+	if level < zerolog.TraceLevel || level > zerolog.Disabled {
+		v.addf(path, "unknown log level %d", level)
+	}
+}
+
+func validateAMQPAddress(v *validation, path string, address string) {
+	if address == "" {
+		v.addf(path, "must not be empty")
+		return
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		v.addf(path, "invalid URL: %v", err)
+		return
+	}
+	if u.Scheme != "amqp" && u.Scheme != "amqps" {
+		v.addf(path, "scheme must be amqp or amqps, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		v.addf(path, "missing host")
+	}
+}
@@ -0,0 +1,168 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/rs/zerolog"
+	"go.uber.org/fx"
+)
+
+func TestValidateDefaults(t *testing.T) {
+	for name, appConfig := range map[string]config.AppConfig{
+		"Local": config.NewLocalAppConfig(),
+		"Test":  config.NewLocalTestAppConfig(),
+	} {
+		if err := appConfig.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	appConfig := config.NewLocalAppConfig()
+	appConfig.CasesService.Port = -1
+	appConfig.CasesStorage.DatabaseType = config.Postgres
+	appConfig.RulesServiceConfig.QueueType = "KAFKA"
+	appConfig.RulesServiceConfig.QueueConfig.SendRetries = -3
+
+	err := appConfig.Validate()
+	var verrs config.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	want := []string{
+		"CasesService.Port",
+		"CasesStorage.Address",
+		"RulesServiceConfig.QueueType",
+		"RulesServiceConfig.QueueConfig.SendRetries",
+	}
+	got := map[string]bool{}
+	for _, fe := range verrs {
+		got[fe.Path] = true
+	}
+	for _, path := range want {
+		if !got[path] {
+			t.Errorf("missing error for %s in %v", path, err)
+		}
+	}
+	if len(verrs) != len(want) {
+		t.Errorf("got %d errors, want %d: %v", len(verrs), len(want), err)
+	}
+}
+
+func TestValidateAMQPAddress(t *testing.T) {
+	for _, address := range []string{"", "http://localhost:5672", "amqp://", "::"} {
+		appConfig := config.NewLocalAppConfig()
+		appConfig.RulesServiceConfig.QueueConfig.Address = address
+		if err := appConfig.Validate(); err == nil {
+			t.Errorf("expected %q to be rejected", address)
+		}
+	}
+}
+
+func TestFxConfigRejectsInvalidConfig(t *testing.T) {
+	appConfig := config.NewLocalAppConfig()
+	appConfig.CasesStorage.Address = ""
+	app := fx.New(config.FxConfig(appConfig), fx.NopLogger)
+	if app.Err() == nil {
+		t.Error("expected fx.App to fail")
+	}
+}
+
+func TestValidateLogSinks(t *testing.T) {
+	appConfig := config.NewLocalAppConfig()
+	appConfig.Log.Sinks = map[string]config.LogSinkConfig{
+		"audit":  {Type: config.SinkFile},
+		"remote": {Type: config.SinkUDP, Address: "syslog"},
+		"Bad":    {Type: config.SinkStdout, Format: "xml"},
+	}
+
+	err := appConfig.Validate()
+	var verrs config.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	got := map[string]bool{}
+	for _, fe := range verrs {
+		got[fe.Path] = true
+	}
+	for _, path := range []string{"Log.Sinks.audit.Path", "Log.Sinks.remote.Address", "Log.Sinks.Bad", "Log.Sinks.Bad.Format"} {
+		if !got[path] {
+			t.Errorf("missing error for %s in %v", path, err)
+		}
+	}
+}
+
+func TestValidateLogRedactRules(t *testing.T) {
+	appConfig := config.NewLocalAppConfig()
+	appConfig.Log.Redact = map[string]config.LogRedactRule{
+		"both":    {Key: "password", Pattern: "x"},
+		"none":    {},
+		"invalid": {Pattern: "("},
+	}
+	err := appConfig.Validate()
+	var verrs config.ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 3 {
+		t.Errorf("expected 3 errors, got %v", err)
+	}
+}
+
+func TestValidateLogRedactHashNeedsKey(t *testing.T) {
+	appConfig := config.NewLocalAppConfig()
+	appConfig.Log.Redact = map[string]config.LogRedactRule{"user": {Key: "user", Hash: true}}
+	var verrs config.ValidationErrors
+	if err := appConfig.Validate(); !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Path != "Log.Redact.user.Hash" {
+		t.Errorf("expected the missing key to be reported, got %v", err)
+	}
+	appConfig.Log.RedactKey = "secret"
+	if err := appConfig.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateLogComponents(t *testing.T) {
+	appConfig := config.NewLocalAppConfig()
+	appConfig.Log.Components = map[string]config.LogComponentLevel{
+		"db":     {Pattern: "cases.db", Level: zerolog.DebugLevel},
+		"cases":  {Pattern: "cases.*"},
+		"all":    {Pattern: "*", Level: zerolog.WarnLevel},
+		"again":  {Pattern: "cases.db"},
+		"broken": {Pattern: "cases..db"},
+	}
+	err := appConfig.Validate()
+	var verrs config.ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 2 {
+		t.Errorf("expected 2 errors, got %v", err)
+	}
+}
+
+func TestValidateFeatureRules(t *testing.T) {
+	appConfig := config.NewLocalAppConfig()
+	appConfig.Features = map[string]config.FeatureConfig{
+		"workflow": {
+			Rollout: config.NewPercentage(101),
+			Rules: []config.FeatureRule{
+				{Name: "acme", Tenant: "acme"},
+				{Name: "acme", Env: config.Env.Prod},
+				{Tenant: "other", Rollout: config.NewPercentage(-1)},
+			},
+		},
+	}
+	err := appConfig.Validate()
+	var verrs config.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	got := map[string]bool{}
+	for _, e := range verrs {
+		got[e.Path] = true
+	}
+	for _, path := range []string{"Features.workflow.Rollout", "Features.workflow.Rules.1.Name", "Features.workflow.Rules.2.Name", "Features.workflow.Rules.2.Rollout"} {
+		if !got[path] {
+			t.Errorf("missing error for %s in %v", path, err)
+		}
+	}
+}
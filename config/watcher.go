@@ -0,0 +1,205 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+)
+
+// reloadDelay groups the bursts of events editors produce when saving a file.
+const reloadDelay = 100 * time.Millisecond
+
+type subscription struct {
+	section string
+	fn      func([]Change)
+}
+
+// Watcher keeps the current AppConfig up to date with its backing file and
+// providers. Reloads happen when the file or a provider key changes, on
+// SIGHUP, or when Reload is called.
+type Watcher struct {
+	opts      []LoadOption
+	file      string
+	providers []providerSource
+	logger    zerolog.Logger
+	current   atomic.Value
+
+	reloadMu  sync.Mutex
+	subsMu    sync.Mutex
+	subs      map[int]subscription
+	nextID    int
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+// NewWatcher loads the initial configuration with opts, which fails the
+// watcher if it is invalid.
+func NewWatcher(logger zerolog.Logger, opts ...LoadOption) (*Watcher, error) {
+	var l loader
+	for _, opt := range opts {
+		opt(&l)
+	}
+	appConfig, _, err := Load(opts...)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		opts:      opts,
+		file:      l.file,
+		providers: l.providers,
+		logger:    logger,
+		subs:      map[int]subscription{},
+		ready:     make(chan struct{}),
+	}
+	w.current.Store(appConfig)
+	return w, nil
+}
+
+// Current returns the latest valid configuration. It is safe to call from any goroutine.
+func (w *Watcher) Current() AppConfig {
+	return w.current.Load().(AppConfig)
+}
+
+// OnChange calls fn with the changes inside section, e.g.
+// "RulesServiceConfig.QueueConfig", after every successful reload that
+// touches it. An empty section subscribes to every change.
+func (w *Watcher) OnChange(section string, fn func([]Change)) (unsubscribe func()) {
+	w.subsMu.Lock()
+	defer w.subsMu.Unlock()
+	id := w.nextID
+	w.nextID++
+	w.subs[id] = subscription{section: section, fn: fn}
+	return func() {
+		w.subsMu.Lock()
+		defer w.subsMu.Unlock()
+		delete(w.subs, id)
+	}
+}
+
+// Ready is closed once Run watches the file and the providers.
+func (w *Watcher) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// Reload re-reads and re-validates the configuration. When that fails the
+// previous configuration stays active and the error is logged and returned.
+// The subscribers are called after the reload completes, so they may call
+// Reload themselves.
+func (w *Watcher) Reload() error {
+	changes, err := w.reload()
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		w.notify(changes)
+	}
+	return nil
+}
+
+func (w *Watcher) reload() ([]Change, error) {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	next, _, err := Load(w.opts...)
+	if err != nil {
+		w.logger.Error().Err(err).Str("file", w.file).Msg("Rejected configuration reload, keeping the previous configuration")
+		return nil, err
+	}
+	changes := Diff(w.Current(), next)
+	w.current.Store(next)
+	if len(changes) > 0 {
+		w.logger.Info().Str("file", w.file).Int("changes", len(changes)).Msg("Reloaded configuration")
+	}
+	return changes, nil
+}
+
+func (w *Watcher) notify(changes []Change) {
+	w.subsMu.Lock()
+	subs := make([]subscription, 0, len(w.subs))
+	for _, s := range w.subs {
+		subs = append(subs, s)
+	}
+	w.subsMu.Unlock()
+
+	for _, s := range subs {
+		var section []Change
+		for _, c := range changes {
+			if c.InSection(s.section) {
+				section = append(section, c)
+			}
+		}
+		if len(section) > 0 {
+			s.fn(section)
+		}
+	}
+}
+
+// Run reloads the configuration on file and provider changes and SIGHUP until
+// ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events chan fsnotify.Event
+	var errs chan error
+	if w.file != "" {
+		fw, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		defer fw.Close()
+		// Watching the directory survives editors that replace the file on save.
+		if err := fw.Add(filepath.Dir(w.file)); err != nil {
+			return fmt.Errorf("watching %s: %w", w.file, err)
+		}
+		events, errs = fw.Events, fw.Errors
+	}
+
+	changed := make(chan string, 1)
+	for _, ps := range w.providers {
+		keys, err := ps.provider.Watch(ctx, ps.prefix)
+		if err != nil {
+			return fmt.Errorf("watching provider %s: %w", ps.provider.Name(), err)
+		}
+		go func() {
+			for key := range keys {
+				sendKey(changed, key)
+			}
+		}()
+	}
+
+	w.readyOnce.Do(func() { close(w.ready) })
+
+	target := filepath.Clean(w.file)
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			w.logger.Info().Msg("Received SIGHUP, reloading configuration")
+			_ = w.Reload()
+		case ev := <-events:
+			if filepath.Clean(ev.Name) == target && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				pending = time.After(reloadDelay)
+			}
+		case <-changed:
+			pending = time.After(reloadDelay)
+		case err := <-errs:
+			w.logger.Warn().Err(err).Str("file", w.file).Msg("Configuration file watch error")
+		case <-pending:
+			pending = nil
+			_ = w.Reload()
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/rs/zerolog"
+)
+
+func newTestWatcher(t *testing.T, content string) (*config.Watcher, string) {
+	t.Helper()
+	path := writeFile(t, "cms.yaml", content)
+	w, err := config.NewWatcher(zerolog.Nop(), config.WithFile(path), config.WithEnviron([]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, path
+}
+
+func rewrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherReloadNotifiesSection(t *testing.T) {
+	w, path := newTestWatcher(t, "rulesServiceConfig:\n  queueConfig:\n    sendRetries: 1\n")
+
+	var queueChanges, casesChanges []config.Change
+	w.OnChange("RulesServiceConfig.QueueConfig", func(c []config.Change) { queueChanges = c })
+	w.OnChange("CasesService", func(c []config.Change) { casesChanges = c })
+
+	rewrite(t, path, "rulesServiceConfig:\n  queueConfig:\n    sendRetries: 9\n    logLevel: error\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queueChanges) != 2 {
+		t.Fatalf("got %d queue changes, want 2: %+v", len(queueChanges), queueChanges)
+	}
+	retries := queueChanges[0]
+	if retries.Path != "RulesServiceConfig.QueueConfig.SendRetries" || retries.Old != 1 || retries.New != 9 {
+		t.Errorf("unexpected change %+v", retries)
+	}
+	if level := queueChanges[1]; level.New != zerolog.ErrorLevel {
+		t.Errorf("unexpected change %+v", level)
+	}
+	if casesChanges != nil {
+		t.Errorf("CasesService subscriber was notified: %+v", casesChanges)
+	}
+	if got := w.Current().RulesServiceConfig.QueueConfig.SendRetries; got != 9 {
+		t.Errorf("Current() SendRetries = %d, want 9", got)
+	}
+}
+
+func TestWatcherRejectsInvalidReload(t *testing.T) {
+	w, path := newTestWatcher(t, "rulesServiceConfig:\n  queueConfig:\n    sendRetries: 1\n")
+	called := false
+	w.OnChange("", func([]config.Change) { called = true })
+
+	rewrite(t, path, "rulesServiceConfig:\n  queueConfig:\n    sendRetries: -1\n")
+	if err := w.Reload(); err == nil {
+		t.Fatal("expected reload to fail")
+	}
+	if got := w.Current().RulesServiceConfig.QueueConfig.SendRetries; got != 1 {
+		t.Errorf("Current() SendRetries = %d, want the previous 1", got)
+	}
+	if called {
+		t.Error("subscriber was notified of a rejected reload")
+	}
+}
+
+func TestWatcherSubscriberMayReload(t *testing.T) {
+	w, path := newTestWatcher(t, "casesService:\n  port: 7000\n")
+	calls := 0
+	w.OnChange("CasesService", func([]config.Change) {
+		calls++
+		if err := w.Reload(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	rewrite(t, path, "casesService:\n  port: 7001\n")
+	done := make(chan error, 1)
+	go func() { done <- w.Reload() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload from a subscriber deadlocked")
+	}
+	if calls != 1 {
+		t.Errorf("subscriber called %d times, want 1", calls)
+	}
+}
+
+func TestWatcherRunReloadsOnWrite(t *testing.T) {
+	w, path := newTestWatcher(t, "casesService:\n  port: 7000\n")
+	changed := make(chan []config.Change, 1)
+	unsubscribe := w.OnChange("CasesService", func(c []config.Change) { changed <- c })
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := w.Run(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer wg.Wait()
+	defer cancel()
+
+	// Readers must be able to run alongside reloads.
+	go func() {
+		for ctx.Err() == nil {
+			_ = w.Current()
+		}
+	}()
+
+	select {
+	case <-w.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not start")
+	}
+	rewrite(t, path, "casesService:\n  port: 7001\n")
+	select {
+	case c := <-changed:
+		if c[0].New != int16(7001) {
+			t.Errorf("unexpected change %+v", c[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration was not reloaded")
+	}
+}
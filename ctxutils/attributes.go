@@ -0,0 +1,39 @@
+package ctxutils
+
+import "context"
+
+type requestAttributeKeyType string
+
+const (
+	tenantKey = requestAttributeKeyType("tenant")
+	userKey   = requestAttributeKeyType("user")
+	caseIDKey = requestAttributeKeyType("case_id")
+)
+
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+func GetTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey).(string)
+	return tenant
+}
+
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+func GetUser(ctx context.Context) string {
+	user, _ := ctx.Value(userKey).(string)
+	return user
+}
+
+// WithCaseID sets the case a request works on, e.g. to key percentage rollouts.
+func WithCaseID(ctx context.Context, caseID string) context.Context {
+	return context.WithValue(ctx, caseIDKey, caseID)
+}
+
+func GetCaseID(ctx context.Context) string {
+	caseID, _ := ctx.Value(caseIDKey).(string)
+	return caseID
+}
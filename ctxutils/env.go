@@ -11,9 +11,15 @@ func WithEnvContext(ctx context.Context, env string) context.Context {
 }
 
 func GetEnvType(ctx context.Context) string {
-	env, ok := ctx.Value(executionCtxKey).(string)
+	env, ok := LookupEnvType(ctx)
 	if ok {
 		return env
 	}
 	return "unkown"
 }
+
+// LookupEnvType is GetEnvType telling whether the context has an environment.
+func LookupEnvType(ctx context.Context) (string, bool) {
+	env, ok := ctx.Value(executionCtxKey).(string)
+	return env, ok
+}
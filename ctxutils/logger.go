@@ -2,23 +2,38 @@ package ctxutils
 
 import (
 	"context"
-
 	"os"
+	"sync"
 
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
+var (
+	consoleLoggerOnce sync.Once
+	consoleLogger     zerolog.Logger
+)
+
+// ContextLogger returns the zerolog logger of ctx and whether it has one.
+// zerolog.DefaultContextLogger does not count.
+func ContextLogger(ctx context.Context) (*zerolog.Logger, bool) {
+	l := zerolog.Ctx(ctx)
+	return l, l != zerolog.Ctx(context.Background())
+}
+
+// WithLoggerConfig returns a context whose logger adds the service names and
+// the environment of ctx to the logger of ctx, or to a console logger when ctx
+// has none. The process-wide zerolog settings are left alone.
 func WithLoggerConfig(ctx context.Context) context.Context {
-	defaultLogger := log.Output(zerolog.ConsoleWriter{
-		Out:     os.Stderr,
-		NoColor: false,
-	}).With().Caller().Logger()
-	zerolog.DefaultContextLogger = &defaultLogger
+	l, ok := ContextLogger(ctx)
+	if !ok {
+		consoleLoggerOnce.Do(func() {
+			consoleLogger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Caller().Logger()
+		})
+		l = &consoleLogger
+	}
 
 	env := GetEnvType(ctx)
 	names := GetServiceNames(ctx)
 
-	ctx = zerolog.Ctx(ctx).With().Interface("service_names", names).Str("env", env).Logger().WithContext(ctx)
-	return ctx
+	return l.With().Interface("service_names", names).Str("env", env).Logger().WithContext(ctx)
 }
@@ -17,9 +17,8 @@ func GetServiceNames(ctx context.Context) *[]string {
 func WithServiceName(ctx context.Context, name string) context.Context {
 	namesAdd := GetServiceNames(ctx)
 	if namesAdd != nil {
-		names := *namesAdd
-		names = append(names, name)
+		names := append((*namesAdd)[:len(*namesAdd):len(*namesAdd)], name)
 		return context.WithValue(ctx, serviceNameKey, names)
 	}
-	return context.WithValue(ctx, serviceNameKey, []string{})
+	return context.WithValue(ctx, serviceNameKey, []string{name})
 }
@@ -0,0 +1,234 @@
+// Package errs creates errors carrying a stack trace, a kind telling whether
+// a retry can succeed, and attributes of the case they concern, for the
+// logger to report them.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// Kind classifies an error.
+type Kind string
+
+const (
+	// Unknown is the kind of the errors not classified.
+	Unknown = Kind("")
+	// Transient errors may go away on retry, e.g. a lost connection.
+	Transient = Kind("transient")
+	// Permanent errors will fail again, e.g. a missing case.
+	Permanent = Kind("permanent")
+	// Validation errors come from invalid input.
+	Validation = Kind("validation")
+)
+
+// Keys of the case attributes of an error.
+const (
+	AttrCaseID = "case_id"
+	AttrAction = "action"
+	AttrTenant = "tenant"
+)
+
+const maxStackDepth = 32
+
+// Error is an error created by this package.
+type Error struct {
+	msg   string
+	err   error
+	kind  Kind
+	attrs map[string]string
+	stack []uintptr
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.err == nil:
+		return e.msg
+	case e.msg == "":
+		return e.err.Error()
+	default:
+		return e.msg + ": " + e.err.Error()
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+func callers() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	// Skips runtime.Callers, callers and the function of this package.
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// New returns an error with the message msg and the current stack.
+func New(msg string) error {
+	return &Error{msg: msg, stack: callers()}
+}
+
+// Errorf formats an error like fmt.Errorf, %w included, with the current
+// stack.
+func Errorf(format string, args ...interface{}) error {
+	return &Error{err: fmt.Errorf(format, args...), stack: callers()}
+}
+
+// Wrap prefixes err with msg and records the current stack. It returns nil
+// when err is nil.
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{msg: msg, err: err, stack: callers()}
+}
+
+// WithKind classifies err. It returns nil when err is nil.
+func WithKind(err error, kind Kind) error {
+	if err == nil {
+		return nil
+	}
+	e := annotate(err)
+	e.kind = kind
+	return e
+}
+
+// WithAttr attaches the attribute key to err, e.g. AttrCaseID. It returns nil
+// when err is nil.
+func WithAttr(err error, key, value string) error {
+	if err == nil {
+		return nil
+	}
+	e := annotate(err)
+	attrs := make(map[string]string, len(e.attrs)+1)
+	for k, v := range e.attrs {
+		attrs[k] = v
+	}
+	attrs[key] = value
+	e.attrs = attrs
+	return e
+}
+
+// annotate returns a copy of err when made by this package, so that the
+// annotations do not lengthen the chain, or else wraps it.
+func annotate(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		c := *e
+		return &c
+	}
+	return &Error{err: err}
+}
+
+// WithCaseID attaches the ID of the case err concerns.
+func WithCaseID(err error, caseID string) error {
+	return WithAttr(err, AttrCaseID, caseID)
+}
+
+// KindOf returns the kind of the outermost classified error of the chain of
+// err.
+func KindOf(err error) Kind {
+	kind := Unknown
+	Walk(err, func(err error, _ int) bool {
+		if e, ok := err.(*Error); ok && e.kind != Unknown {
+			kind = e.kind
+			return false
+		}
+		return true
+	})
+	return kind
+}
+
+// IsTransient tells whether a retry of what failed with err may succeed.
+func IsTransient(err error) bool {
+	return KindOf(err) == Transient
+}
+
+// Attrs returns the attributes of the chain of err, the outer ones winning.
+func Attrs(err error) map[string]string {
+	attrs := map[string]string{}
+	Walk(err, func(err error, _ int) bool {
+		if e, ok := err.(*Error); ok {
+			for k, v := range e.attrs {
+				if _, ok := attrs[k]; !ok {
+					attrs[k] = v
+				}
+			}
+		}
+		return true
+	})
+	return attrs
+}
+
+// Stack returns the deepest stack recorded in the chain of err, which is the
+// closest to where the error happened, or nil.
+func Stack(err error) []runtime.Frame {
+	var stack []uintptr
+	Walk(err, func(err error, _ int) bool {
+		if e, ok := err.(*Error); ok && e.stack != nil {
+			stack = e.stack
+		}
+		return true
+	})
+	if stack == nil {
+		return nil
+	}
+	var frames []runtime.Frame
+	it := runtime.CallersFrames(stack)
+	for {
+		f, more := it.Next()
+		frames = append(frames, f)
+		if !more {
+			return frames
+		}
+	}
+}
+
+// FormatFrame formats f as "function file:line".
+func FormatFrame(f runtime.Frame) string {
+	return fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line)
+}
+
+// Walk calls fn for err and the errors it wraps, depth first, including the
+// branches of errors.Join, until fn returns false.
+func Walk(err error, fn func(err error, depth int) bool) {
+	walk(err, 0, fn)
+}
+
+func walk(err error, depth int, fn func(error, int) bool) bool {
+	if err == nil {
+		return true
+	}
+	if !fn(err, depth) {
+		return false
+	}
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return walk(u.Unwrap(), depth+1, fn)
+	case interface{ Unwrap() []error }:
+		for _, e := range u.Unwrap() {
+			if !walk(e, depth+1, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Chain returns the messages of err and the errors it wraps, as walked by Walk,
+// skipping the wrappers adding nothing to the message.
+func Chain(err error) []string {
+	var chain []string
+	Walk(err, func(err error, _ int) bool {
+		if !Redundant(err) {
+			chain = append(chain, err.Error())
+		}
+		return true
+	})
+	return chain
+}
+
+// Redundant tells whether err only wraps an error with the same message.
+func Redundant(err error) bool {
+	u := errors.Unwrap(err)
+	return u != nil && u.Error() == err.Error()
+}
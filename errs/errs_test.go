@@ -0,0 +1,54 @@
+package errs_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/case-management-suite/common/errs"
+)
+
+func loadCase() error {
+	return errs.WithKind(errs.Wrap(io.ErrUnexpectedEOF, "read case"), errs.Transient)
+}
+
+func TestWrapChain(t *testing.T) {
+	err := errs.WithCaseID(fmt.Errorf("approve: %w", loadCase()), "case-7")
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("the cause is lost")
+	}
+	if err.Error() != "approve: read case: unexpected EOF" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if !errs.IsTransient(err) {
+		t.Errorf("kind = %s", errs.KindOf(err))
+	}
+	if got := errs.Attrs(err); got[errs.AttrCaseID] != "case-7" {
+		t.Errorf("Attrs = %v", got)
+	}
+	want := []string{"approve: read case: unexpected EOF", "read case: unexpected EOF", "unexpected EOF"}
+	if got := errs.Chain(err); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Chain = %q, want %q", got, want)
+	}
+
+	stack := errs.Stack(err)
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, "errs_test.loadCase") {
+		t.Errorf("stack does not start in loadCase: %v", stack)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	err := errors.Join(errs.WithKind(errs.New("bad status"), errs.Validation), errs.New("no assignee"))
+	if errs.KindOf(err) != errs.Validation {
+		t.Errorf("kind = %s", errs.KindOf(err))
+	}
+	if got := errs.Chain(err); len(got) != 3 || got[1] != "bad status" || got[2] != "no assignee" {
+		t.Errorf("Chain = %q", got)
+	}
+	if errs.KindOf(errors.New("plain")) != errs.Unknown || errs.Wrap(nil, "x") != nil {
+		t.Error("unexpected classification of plain errors")
+	}
+}
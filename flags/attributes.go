@@ -0,0 +1,58 @@
+package flags
+
+import (
+	"context"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/ctxutils"
+)
+
+// Attributes are the properties of a request the rules of a flag match on.
+type Attributes struct {
+	Env      config.EnvType
+	Services []string
+	Tenant   string
+	User     string
+	CaseID   string
+}
+
+// AttributesFromContext reads the attributes set with ctxutils, using env
+// when the context has no environment.
+func AttributesFromContext(ctx context.Context, env config.EnvType) Attributes {
+	attrs := Attributes{
+		Env:    env,
+		Tenant: ctxutils.GetTenant(ctx),
+		User:   ctxutils.GetUser(ctx),
+		CaseID: ctxutils.GetCaseID(ctx),
+	}
+	if e, ok := ctxutils.LookupEnvType(ctx); ok {
+		attrs.Env = config.EnvType(e)
+	}
+	if names := ctxutils.GetServiceNames(ctx); names != nil {
+		attrs.Services = *names
+	}
+	return attrs
+}
+
+// Matches tells whether the attributes satisfy every condition of rule.
+func (a Attributes) Matches(rule config.FeatureRule) bool {
+	if rule.Env != "" && rule.Env != a.Env {
+		return false
+	}
+	if rule.Service != "" && !contains(a.Services, rule.Service) {
+		return false
+	}
+	if rule.Tenant != "" && rule.Tenant != a.Tenant {
+		return false
+	}
+	return rule.User == "" || rule.User == a.User
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
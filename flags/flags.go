@@ -0,0 +1,245 @@
+// Package flags evaluates feature flags declared in code with typed defaults
+// and set in the Features section of the AppConfig.
+package flags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/logger"
+	"github.com/case-management-suite/common/metrics"
+)
+
+// Value lists the types a flag can have.
+type Value interface {
+	bool | int | float64 | string | time.Duration
+}
+
+// Reasons of an Evaluation.
+const (
+	ReasonDefault  = "default"
+	ReasonConfig   = "config"
+	ReasonRule     = "rule"
+	ReasonRollout  = "rollout"
+	ReasonBadValue = "bad_value"
+)
+
+// EvaluationEvent is the metric event counted for every evaluation of flag.
+func EvaluationEvent(flag string) metrics.MetricEvent {
+	return metrics.MetricEvent("FEATURE_FLAG_EVALUATED:" + flag)
+}
+
+// Registry holds the declared flags and their settings.
+type Registry struct {
+	env     config.EnvType
+	logger  logger.Logger
+	metrics metrics.MetricsService
+
+	mu       sync.RWMutex
+	features map[string]config.FeatureConfig
+	checks   map[string]func(string) error
+}
+
+// NewRegistry returns a registry with the Features of appConfig. Requests
+// without an environment in their context are evaluated in appConfig.Env.
+func NewRegistry(appConfig config.AppConfig, l logger.Logger, ms metrics.MetricsService) *Registry {
+	return &Registry{
+		env:      appConfig.Env,
+		logger:   l,
+		metrics:  ms,
+		features: appConfig.Features,
+		checks:   map[string]func(string) error{},
+	}
+}
+
+// Update replaces the settings of the flags, e.g. after a configuration
+// reload. The settings are kept when a value does not parse as its flag.
+func (r *Registry) Update(features map[string]config.FeatureConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, check := range r.checks {
+		if err := checkFeature(name, features[name], check); err != nil {
+			return err
+		}
+	}
+	r.features = features
+	return nil
+}
+
+// Watch updates the registry whenever w reloads a configuration with
+// different Features.
+func (r *Registry) Watch(w *config.Watcher) (unsubscribe func()) {
+	return w.OnChange("Features", func([]config.Change) {
+		if err := r.Update(w.Current().Features); err != nil {
+			r.logger.Error().Err(err).Msg("Rejected feature flags update")
+		}
+	})
+}
+
+func (r *Registry) feature(name string) (config.FeatureConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fc, ok := r.features[name]
+	return fc, ok
+}
+
+func checkFeature(name string, fc config.FeatureConfig, check func(string) error) error {
+	if fc.Value != "" {
+		if err := check(fc.Value); err != nil {
+			return fmt.Errorf("feature %s: %w", name, err)
+		}
+	}
+	for _, r := range fc.Rules {
+		if r.Value != "" {
+			if err := check(r.Value); err != nil {
+				return fmt.Errorf("feature %s rule %s: %w", name, r.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Flag is a feature flag of type T.
+type Flag[T Value] struct {
+	name     string
+	def      T
+	registry *Registry
+}
+
+// Define declares the flag name with its default value. It fails when the flag
+// is already declared or its configured values do not parse as T.
+func Define[T Value](r *Registry, name string, def T) (*Flag[T], error) {
+	check := func(s string) error {
+		_, err := parse[T](s)
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if name == "" {
+		return nil, fmt.Errorf("flag name must not be empty")
+	}
+	if _, ok := r.checks[name]; ok {
+		return nil, fmt.Errorf("flag %s is already defined", name)
+	}
+	if err := checkFeature(name, r.features[name], check); err != nil {
+		return nil, err
+	}
+	r.checks[name] = check
+	return &Flag[T]{name: name, def: def, registry: r}, nil
+}
+
+func (f *Flag[T]) Name() string {
+	return f.name
+}
+
+func (f *Flag[T]) Default() T {
+	return f.def
+}
+
+// Evaluation is the value of a flag for a request and why it was chosen.
+type Evaluation[T Value] struct {
+	Value  T
+	Reason string
+	// Rule is the name of the matching rule when Reason is ReasonRule, or
+	// ReasonRollout and the rule's rollout excluded the case.
+	Rule string
+}
+
+// Get returns the value of the flag for the request of ctx.
+func (f *Flag[T]) Get(ctx context.Context) T {
+	return f.Evaluate(ctx).Value
+}
+
+// Evaluate resolves the flag for the request of ctx, logs and counts the
+// evaluation.
+func (f *Flag[T]) Evaluate(ctx context.Context) Evaluation[T] {
+	attrs := AttributesFromContext(ctx, f.registry.env)
+	ev := f.evaluate(attrs)
+
+	f.registry.logger.Debug().
+		Str("flag", f.name).
+		Interface("value", ev.Value).
+		Str("reason", ev.Reason).
+		Str("rule", ev.Rule).
+		Str("case_id", attrs.CaseID).
+		Msg("Feature flag evaluated")
+	if f.registry.metrics != nil {
+		f.registry.metrics.LogEvent(EvaluationEvent(f.name))
+	}
+	return ev
+}
+
+func (f *Flag[T]) evaluate(attrs Attributes) Evaluation[T] {
+	ev := Evaluation[T]{Value: f.def, Reason: ReasonDefault}
+	fc, ok := f.registry.feature(f.name)
+	if !ok {
+		return ev
+	}
+
+	value, rollout, reason := fc.Value, fc.Rollout, ReasonConfig
+	for _, rule := range fc.Rules {
+		if attrs.Matches(rule) {
+			value, rollout, reason, ev.Rule = rule.Value, rule.Rollout, ReasonRule, rule.Name
+			break
+		}
+	}
+
+	if value == "" {
+		return ev
+	}
+	if percent, ok := rollout.Get(); ok && !InRollout(f.name, attrs.CaseID, percent) {
+		ev.Reason = ReasonRollout
+		return ev
+	}
+	parsed, err := parse[T](value)
+	if err != nil {
+		f.registry.logger.Error().Err(err).Str("flag", f.name).Msg("Invalid feature flag value, using the default")
+		ev.Reason = ReasonBadValue
+		return ev
+	}
+	ev.Value, ev.Reason = parsed, reason
+	return ev
+}
+
+// InRollout tells whether caseID is in the first percent of the cases for
+// flag. The buckets are stable, so that a case keeps its value as the
+// percentage grows. 0 includes no case and 100 every case; with a partial
+// rollout a request without case is excluded.
+func InRollout(flag, caseID string, percent int) bool {
+	if percent >= 100 {
+		return true
+	}
+	if percent <= 0 || caseID == "" {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(flag + "/" + caseID))
+	return int(h.Sum32()%100) < percent
+}
+
+func parse[T Value](s string) (T, error) {
+	var value T
+	var parsed interface{}
+	var err error
+	switch any(value).(type) {
+	case bool:
+		parsed, err = strconv.ParseBool(s)
+	case int:
+		parsed, err = strconv.Atoi(s)
+	case float64:
+		parsed, err = strconv.ParseFloat(s, 64)
+	case string:
+		parsed = s
+	case time.Duration:
+		parsed, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return value, fmt.Errorf("invalid %T value %q", value, s)
+	}
+	return parsed.(T), nil
+}
@@ -0,0 +1,198 @@
+package flags_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/ctxutils"
+	"github.com/case-management-suite/common/flags"
+	"github.com/case-management-suite/common/logger"
+	"github.com/case-management-suite/common/metrics"
+)
+
+type countingMetrics struct {
+	mu     sync.Mutex
+	events map[metrics.MetricEvent]int
+}
+
+func (m *countingMetrics) LogEvent(event metrics.MetricEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events[event]++
+}
+
+func newRegistry(t *testing.T, features map[string]config.FeatureConfig) (*flags.Registry, *countingMetrics) {
+	t.Helper()
+	appConfig := config.NewLocalTestAppConfig()
+	appConfig.Features = features
+	ms := &countingMetrics{events: map[metrics.MetricEvent]int{}}
+	return flags.NewRegistry(appConfig, logger.NewTestLogger(), ms), ms
+}
+
+func TestFlagDefaultsAndConfig(t *testing.T) {
+	r, ms := newRegistry(t, map[string]config.FeatureConfig{
+		"new-triage":     {Value: "true"},
+		"triage-timeout": {Value: "90s"},
+	})
+	triage, err := flags.Define(r, "new-triage", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout, _ := flags.Define(r, "triage-timeout", time.Minute)
+	retries, _ := flags.Define(r, "triage-retries", 3)
+
+	ctx := context.Background()
+	if ev := triage.Evaluate(ctx); !ev.Value || ev.Reason != flags.ReasonConfig {
+		t.Errorf("new-triage = %+v", ev)
+	}
+	if got := timeout.Get(ctx); got != 90*time.Second {
+		t.Errorf("triage-timeout = %s", got)
+	}
+	if ev := retries.Evaluate(ctx); ev.Value != 3 || ev.Reason != flags.ReasonDefault {
+		t.Errorf("triage-retries = %+v", ev)
+	}
+	if n := ms.events[flags.EvaluationEvent("new-triage")]; n != 1 {
+		t.Errorf("%d evaluations of new-triage counted, want 1", n)
+	}
+
+	if _, err := flags.Define(r, "new-triage", true); err == nil {
+		t.Error("expected a duplicate flag to fail")
+	}
+	if _, err := flags.Define(r, "triage-timeout-ms", 0); err != nil {
+		t.Errorf("unconfigured flag: %v", err)
+	}
+}
+
+func TestDefineRejectsBadValue(t *testing.T) {
+	r, _ := newRegistry(t, map[string]config.FeatureConfig{
+		"new-triage": {Rules: []config.FeatureRule{{Name: "acme", Tenant: "acme", Value: "maybe"}}},
+	})
+	if _, err := flags.Define(r, "new-triage", false); err == nil {
+		t.Error("expected a non-boolean value to fail")
+	}
+}
+
+func TestFlagRules(t *testing.T) {
+	r, _ := newRegistry(t, map[string]config.FeatureConfig{
+		"workflow": {
+			Value: "v1",
+			Rules: []config.FeatureRule{
+				{Name: "acme", Tenant: "acme", Value: "v3"},
+				{Name: "prod", Env: config.Env.Prod, Value: "v2"},
+				{Name: "rules-user", Service: "rules", User: "ana", Value: "v4"},
+			},
+		},
+	})
+	workflow, err := flags.Define(r, "workflow", "v0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want string
+		rule string
+	}{
+		{"NoMatch", context.Background(), "v1", ""},
+		{"Env", ctxutils.WithEnvContext(context.Background(), "prod"), "v2", "prod"},
+		{"FirstRuleWins", ctxutils.WithTenant(ctxutils.WithEnvContext(context.Background(), "prod"), "acme"), "v3", "acme"},
+		{"ServiceAndUser", ctxutils.WithUser(ctxutils.WithServiceName(context.Background(), "rules"), "ana"), "v4", "rules-user"},
+		{"ServiceOnly", ctxutils.WithServiceName(context.Background(), "rules"), "v1", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if ev := workflow.Evaluate(c.ctx); ev.Value != c.want || ev.Rule != c.rule {
+				t.Errorf("Evaluate = %+v, want %s from rule %q", ev, c.want, c.rule)
+			}
+		})
+	}
+}
+
+func TestRulesApplyInListOrder(t *testing.T) {
+	r, _ := newRegistry(t, map[string]config.FeatureConfig{
+		"workflow": {
+			Rules: []config.FeatureRule{
+				{Name: "prod", Env: config.Env.Prod, Value: "v2"},
+				{Name: "acme", Tenant: "acme", Value: "v3"},
+			},
+		},
+	})
+	workflow, _ := flags.Define(r, "workflow", "v0")
+	ctx := ctxutils.WithTenant(ctxutils.WithEnvContext(context.Background(), "prod"), "acme")
+	if ev := workflow.Evaluate(ctx); ev.Value != "v2" || ev.Rule != "prod" {
+		t.Errorf("Evaluate = %+v, want v2 from the first rule", ev)
+	}
+}
+
+func TestZeroRolloutExcludesEveryCase(t *testing.T) {
+	r, _ := newRegistry(t, map[string]config.FeatureConfig{
+		"new-triage": {Value: "true", Rollout: config.NewPercentage(0)},
+		"workflow":   {Value: "v1"},
+	})
+	triage, _ := flags.Define(r, "new-triage", false)
+	workflow, _ := flags.Define(r, "workflow", "v0")
+	for i := 0; i < 100; i++ {
+		ctx := ctxutils.WithCaseID(context.Background(), fmt.Sprintf("case-%d", i))
+		if ev := triage.Evaluate(ctx); ev.Value || ev.Reason != flags.ReasonRollout {
+			t.Fatalf("case-%d = %+v with a 0%% rollout", i, ev)
+		}
+		if workflow.Get(ctx) != "v1" {
+			t.Fatalf("case-%d did not get the value without rollout", i)
+		}
+	}
+}
+
+func TestRolloutIsDeterministic(t *testing.T) {
+	r, _ := newRegistry(t, map[string]config.FeatureConfig{
+		"new-triage": {Value: "true", Rollout: config.NewPercentage(30)},
+	})
+	triage, _ := flags.Define(r, "new-triage", false)
+
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		ctx := ctxutils.WithCaseID(context.Background(), fmt.Sprintf("case-%d", i))
+		first := triage.Get(ctx)
+		if triage.Get(ctx) != first {
+			t.Fatalf("case-%d got different values", i)
+		}
+		if first {
+			enabled++
+		}
+	}
+	if enabled < 250 || enabled > 350 {
+		t.Errorf("%d of 1000 cases enabled, want about 300", enabled)
+	}
+	if ev := triage.Evaluate(context.Background()); ev.Value || ev.Reason != flags.ReasonRollout {
+		t.Errorf("request without case = %+v", ev)
+	}
+
+	for i := 0; i < 100; i++ {
+		caseID := fmt.Sprintf("case-%d", i)
+		if flags.InRollout("new-triage", caseID, 30) && !flags.InRollout("new-triage", caseID, 60) {
+			t.Errorf("%s left the rollout when it grew", caseID)
+		}
+	}
+}
+
+func TestRegistryUpdate(t *testing.T) {
+	r, _ := newRegistry(t, nil)
+	triage, _ := flags.Define(r, "new-triage", false)
+	if triage.Get(context.Background()) {
+		t.Fatal("expected the default")
+	}
+
+	if err := r.Update(map[string]config.FeatureConfig{"new-triage": {Value: "yes"}}); err == nil {
+		t.Error("expected an invalid value to be rejected")
+	}
+	if err := r.Update(map[string]config.FeatureConfig{"new-triage": {Value: "true"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !triage.Get(context.Background()) {
+		t.Error("update not applied")
+	}
+}
@@ -0,0 +1,22 @@
+package flags
+
+import (
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/logger"
+	"github.com/case-management-suite/common/metrics"
+	"go.uber.org/fx"
+)
+
+type fxRegistryParams struct {
+	fx.In
+	AppConfig config.AppConfig
+	Metrics   metrics.MetricsService `optional:"true"`
+}
+
+// FxRegistry provides the *Registry of the supplied AppConfig, counting the
+// evaluations with the MetricsService when the application has one.
+func FxRegistry() fx.Option {
+	return fx.Provide(func(p fxRegistryParams) *Registry {
+		return NewRegistry(p.AppConfig, logger.NewServiceLoggerFromConfig("flags", p.AppConfig), p.Metrics)
+	})
+}
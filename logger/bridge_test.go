@@ -0,0 +1,72 @@
+package logger_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/logger"
+	"github.com/case-management-suite/common/logger/logtest"
+	"github.com/rs/zerolog"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
+	"go.uber.org/zap"
+)
+
+func TestSlogHandler(t *testing.T) {
+	l, rec := logtest.NewT(t)
+	sl := l.Slog().With("service", "rules")
+	sl.Debug("Connecting", "attempt", 2, "timeout", time.Second)
+	sl.WithGroup("amqp").With("vhost", "/").Error("Publish failed", "err", errors.New("closed"), slog.Group("queue", "name", "actions"))
+
+	rec.AssertLogged(t, logtest.Level(zerolog.DebugLevel), logtest.Message("Connecting"),
+		logtest.Field("service", "rules"), logtest.Field("attempt", 2), logtest.Field("timeout", 1000))
+	rec.AssertLogged(t, logtest.Level(zerolog.ErrorLevel), logtest.Message("Publish failed"),
+		logtest.Field("service", "rules"),
+		logtest.Field("amqp", map[string]interface{}{
+			"vhost": "/",
+			"err":   "closed",
+			"queue": map[string]interface{}{"name": "actions"},
+		}))
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	l, rec := logtest.New()
+	sl := logger.Logger{Logger: l.Level(zerolog.WarnLevel)}.Slog()
+	sl.Info("hidden")
+	sl.Warn("shown")
+	if n := len(rec.Entries()); n != 1 {
+		t.Errorf("%d lines, want 1", n)
+	}
+}
+
+func TestZapCore(t *testing.T) {
+	l, rec := logtest.NewT(t)
+	zl := l.Zap().Named("amqp").With(zap.String("service", "rules"))
+	zl.Warn("Reconnecting", zap.Int("attempt", 3), zap.Duration("backoff", time.Second))
+	zl.Debug("Heartbeat")
+
+	rec.AssertLogged(t, logtest.Level(zerolog.WarnLevel), logtest.Message("Reconnecting"),
+		logtest.Field("logger", "amqp"), logtest.Field("service", "rules"), logtest.Field("attempt", 3))
+	rec.AssertLogged(t, logtest.Level(zerolog.DebugLevel), logtest.Message("Heartbeat"))
+}
+
+func TestFxEventLogger(t *testing.T) {
+	l, rec := logtest.NewT(t)
+	app := fx.New(
+		fx.WithLogger(func() fxevent.Logger { return logger.NewFxEventLogger(l) }),
+		fx.Provide(func() int { return 1 }),
+		fx.Invoke(func(int) {}),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rec.AssertLogged(t, logtest.Message("provided"), logtest.Field("type", "int"))
+}
+
+func TestStdLogger(t *testing.T) {
+	l, rec := logtest.NewT(t)
+	l.StdLogger(zerolog.WarnLevel).Printf("http: TLS handshake error from %s", "10.0.0.1")
+	rec.AssertLogged(t, logtest.Level(zerolog.WarnLevel), logtest.Message("http: TLS handshake error from 10.0.0.1"))
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/ctxutils"
+)
+
+// Names of the correlation fields of the requests, see WithFields.
+const (
+	FieldCaseID    = "case_id"
+	FieldAction    = "action"
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+)
+
+// envLoggers caches the loggers of FromContext for the contexts without one.
+var envLoggers sync.Map
+
+// WithLogger returns a context carrying l. Like zerolog, it does not store a
+// disabled logger in a context without logger.
+func WithLogger(ctx context.Context, l Logger) context.Context {
+	return l.Logger.WithContext(ctx)
+}
+
+// FromContext returns the logger of the request of ctx, with the fields added
+// by WithFields. A context without logger gets one for its environment,
+// tagged with its environment and service names.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctxutils.ContextLogger(ctx); ok {
+		return Logger{Logger: *l}
+	}
+	env, ok := ctxutils.LookupEnvType(ctx)
+	cached, found := envLoggers.Load(env)
+	if !found {
+		var l Logger
+		if ok {
+			l = NewLogger(config.EnvType(env))
+		} else {
+			l = BuildDefaultLogger()
+		}
+		// The sinks are shared, the logger of a racing call is merely dropped.
+		cached, _ = envLoggers.LoadOrStore(env, l)
+	}
+	c := cached.(Logger).With()
+	if names := ctxutils.GetServiceNames(ctx); names != nil {
+		c = c.Strs("service_names", *names)
+	}
+	return Logger{Logger: c.Logger()}
+}
+
+// WithFields returns a context whose logger adds the fields, given as
+// alternating keys and values, e.g.
+//
+//	ctx = logger.WithFields(ctx, logger.FieldCaseID, id, logger.FieldAction, action)
+func WithFields(ctx context.Context, keyvals ...interface{}) context.Context {
+	l := FromContext(ctx)
+	return WithLogger(ctx, Logger{Logger: l.With().Fields(keyvals).Logger()})
+}
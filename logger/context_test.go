@@ -0,0 +1,72 @@
+package logger_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/case-management-suite/common/ctxutils"
+	"github.com/case-management-suite/common/logger"
+	"github.com/case-management-suite/common/logger/logtest"
+	"github.com/rs/zerolog"
+)
+
+func TestWithFields(t *testing.T) {
+	l, rec := logtest.NewT(t)
+	ctx := logger.WithLogger(context.Background(), l)
+	ctx = logger.WithFields(ctx, logger.FieldRequestID, "req-1", logger.FieldTraceID, "trace-1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			caseCtx := logger.WithFields(ctx, logger.FieldCaseID, fmt.Sprintf("case-%d", i), logger.FieldAction, "approve")
+			l := logger.FromContext(caseCtx)
+			l.Info().Msg("Action applied")
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 5; i++ {
+		rec.AssertLogged(t,
+			logtest.Message("Action applied"),
+			logtest.Field(logger.FieldCaseID, fmt.Sprintf("case-%d", i)),
+			logtest.Field(logger.FieldRequestID, "req-1"),
+			logtest.Field(logger.FieldTraceID, "trace-1"),
+			logtest.Field(logger.FieldAction, "approve"),
+		)
+	}
+	if n := len(rec.Filter(logtest.HasField(logger.FieldCaseID))); n != 5 {
+		t.Errorf("%d lines with a case ID, want 5", n)
+	}
+
+	parent := logger.FromContext(ctx)
+	parent.Info().Msg("Request done")
+	rec.AssertNotLogged(t, logtest.Message("Request done"), logtest.HasField(logger.FieldCaseID))
+}
+
+func TestFromContextWithoutLogger(t *testing.T) {
+	ctx := ctxutils.WithServiceName(ctxutils.WithEnvContext(context.Background(), "test"), "cases")
+	l := logger.FromContext(ctx)
+	if l.GetLevel() == zerolog.Disabled {
+		t.Error("expected a default logger")
+	}
+	if zerolog.DefaultContextLogger != nil {
+		t.Error("the default context logger was set")
+	}
+}
+
+func TestWithLoggerConfigKeepsContextLogger(t *testing.T) {
+	l, rec := logtest.New()
+	ctx := logger.WithLogger(context.Background(), l)
+	ctx = ctxutils.DecorateContext(ctx, ctxutils.ContextDecoration{Name: "rules"})
+
+	decorated := logger.FromContext(ctx)
+	decorated.Info().Msg("Started")
+	rec.AssertLogged(t, logtest.Message("Started"), logtest.Field("service_names", []string{"rules"}))
+	if zerolog.DefaultContextLogger != nil {
+		t.Error("the default context logger was set")
+	}
+}
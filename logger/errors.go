@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/case-management-suite/common/errs"
+	"github.com/rs/zerolog"
+)
+
+// Names of the fields added by WithError.
+const (
+	FieldErrorChain = "error_chain"
+	FieldErrorKind  = "error_kind"
+	FieldErrorAttrs = "error_attrs"
+	FieldStack      = "stack"
+)
+
+// WithError adds err to e with the messages and types of its chain, its
+// kind, its stack and its case attributes when made with the errs package.
+// The attributes are nested under FieldErrorAttrs, not to clash with the
+// fields of e.
+func WithError(e *zerolog.Event, err error) *zerolog.Event {
+	if err == nil || e == nil {
+		return e
+	}
+	e = e.Err(err)
+
+	chain := zerolog.Arr()
+	errs.Walk(err, func(err error, depth int) bool {
+		if errs.Redundant(err) {
+			return true
+		}
+		chain.Dict(zerolog.Dict().
+			Str("type", fmt.Sprintf("%T", err)).
+			Str("error", err.Error()).
+			Int("depth", depth))
+		return true
+	})
+	e = e.Array(FieldErrorChain, chain)
+
+	if kind := errs.KindOf(err); kind != errs.Unknown {
+		e = e.Str(FieldErrorKind, string(kind))
+	}
+	if frames := errs.Stack(err); frames != nil {
+		stack := make([]string, len(frames))
+		for i, f := range frames {
+			stack[i] = errs.FormatFrame(f)
+		}
+		e = e.Strs(FieldStack, stack)
+	}
+	attrs := errs.Attrs(err)
+	if len(attrs) == 0 {
+		return e
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	dict := zerolog.Dict()
+	for _, k := range keys {
+		dict = dict.Str(k, attrs[k])
+	}
+	return e.Dict(FieldErrorAttrs, dict)
+}
+
+// Failure returns an error event for err, see WithError.
+func (l Logger) Failure(err error) *zerolog.Event {
+	return WithError(l.Logger.Error(), err)
+}
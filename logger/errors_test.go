@@ -0,0 +1,71 @@
+package logger_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/case-management-suite/common/errs"
+	"github.com/case-management-suite/common/logger"
+	"github.com/case-management-suite/common/logger/logtest"
+	"github.com/rs/zerolog"
+)
+
+func TestFailure(t *testing.T) {
+	l, rec := logtest.NewT(t)
+	_, cause := os.Open("/does/not/exist")
+	err := errs.WithCaseID(errs.WithKind(errs.Wrap(cause, "load rules"), errs.Permanent), "case-9")
+	err = fmt.Errorf("start rules server: %w", err)
+	l.Failure(err).Msg("Failed to start")
+
+	e := rec.AssertLogged(t, logtest.Level(zerolog.ErrorLevel), logtest.Message("Failed to start"),
+		logtest.Field(logger.FieldErrorKind, "permanent"),
+		logtest.Field(logger.FieldErrorAttrs, map[string]string{errs.AttrCaseID: "case-9"}),
+		logtest.Field("error", err.Error()))
+
+	chain, _ := e.Fields[logger.FieldErrorChain].([]interface{})
+	if len(chain) != 4 {
+		t.Fatalf("chain %v, want 4 links", chain)
+	}
+	last := chain[3].(map[string]interface{})
+	if last["type"] != "syscall.Errno" || last["depth"] != float64(3) {
+		t.Errorf("unexpected cause %v", last)
+	}
+	stack, _ := e.Fields[logger.FieldStack].([]interface{})
+	if len(stack) == 0 || !strings.Contains(stack[0].(string), "TestFailure") {
+		t.Errorf("unexpected stack %v", stack)
+	}
+}
+
+func TestWithErrorPlain(t *testing.T) {
+	l, rec := logtest.New()
+	logger.WithError(l.Warn(), fmt.Errorf("retrying")).Msg("Publish failed")
+	logger.WithError(l.Warn(), nil).Msg("No error")
+
+	rec.AssertLogged(t, logtest.Message("Publish failed"), logtest.Field("error", "retrying"))
+	rec.AssertNotLogged(t, logtest.HasField(logger.FieldStack))
+	rec.AssertNotLogged(t, logtest.HasField(logger.FieldErrorKind))
+	rec.AssertNotLogged(t, logtest.Message("No error"), logtest.HasField("error"))
+	rec.AssertNotLogged(t, logtest.HasField(logger.FieldErrorAttrs))
+}
+
+func TestWithErrorAttrsDoNotShadowFields(t *testing.T) {
+	l, rec := logtest.New()
+	err := errs.WithAttr(errs.WithAttr(errs.New("boom"), "level", "debug"), "message", "fake")
+	logger.WithError(l.Error(), err).Msg("Failed")
+
+	rec.AssertLogged(t, logtest.Level(zerolog.ErrorLevel), logtest.Message("Failed"),
+		logtest.Field(logger.FieldErrorAttrs, map[string]string{"level": "debug", "message": "fake"}))
+}
+
+func TestWithErrorDisabledEvent(t *testing.T) {
+	l, rec := logtest.New()
+	l = logger.Logger{Logger: l.Level(zerolog.InfoLevel)}
+	if e := logger.WithError(l.Debug(), errs.New("boom")); e != nil {
+		t.Error("expected the disabled event to stay nil")
+	}
+	if len(rec.Entries()) != 0 {
+		t.Errorf("unexpected lines %v", rec.Entries())
+	}
+}
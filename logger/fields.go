@@ -0,0 +1,14 @@
+package logger
+
+// Names of the fields every service logs, whatever the format.
+const (
+	FieldService = "service"
+	FieldEnv     = "env"
+	// FieldComponent names the part of a service, see WithComponent.
+	FieldComponent = "component"
+)
+
+// WithTraceID returns a logger adding the trace ID to every line.
+func (l Logger) WithTraceID(id string) Logger {
+	return Logger{Logger: l.With().Str(FieldTraceID, id).Logger()}
+}
@@ -0,0 +1,17 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/case-management-suite/common/config"
+	"go.uber.org/fx"
+)
+
+// FxSinks holds the sinks of the loggers of the supplied AppConfig until the
+// application stops.
+func FxSinks() fx.Option {
+	return fx.Invoke(func(lc fx.Lifecycle, appConfig config.AppConfig) {
+		hold := HoldSinks(appConfig.Log)
+		lc.Append(fx.Hook{OnStop: func(context.Context) error { return hold.Close() }})
+	})
+}
@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LevelControl changes the levels of a registry at runtime and logs who
+// changed what. It serves them over HTTP for the operators it authenticates:
+//
+//	GET                                      lists the levels
+//	PUT {"component": "cases.db", "level": "debug", "ttl": "10m"}
+//	                                         sets a level, AllComponents when
+//	                                         component is empty, for ttl if set
+//	DELETE ?component=cases.db               removes a level
+type LevelControl struct {
+	levels       *Levels
+	logger       Logger
+	authenticate Authenticator
+}
+
+// Authenticator returns the verified identity of the author of a request, or
+// an error when the request must be rejected.
+type Authenticator func(*http.Request) (string, error)
+
+// BasicAuthenticator authenticates the requests whose basic auth credentials
+// pass check, as their user.
+func BasicAuthenticator(check func(user, password string) bool) Authenticator {
+	return func(r *http.Request) (string, error) {
+		user, password, ok := r.BasicAuth()
+		if !ok || !check(user, password) {
+			return "", errors.New("invalid credentials")
+		}
+		return user, nil
+	}
+}
+
+// NewLevelControl serves the levels to the requests authenticated by
+// authenticate, rejecting them all when it is nil.
+func NewLevelControl(levels *Levels, l Logger, authenticate Authenticator) *LevelControl {
+	return &LevelControl{levels: levels, logger: l, authenticate: authenticate}
+}
+
+// ComponentLevel is the level of a component pattern.
+type ComponentLevel struct {
+	Component string     `json:"component"`
+	Level     string     `json:"level"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// List returns the levels by component pattern.
+func (c *LevelControl) List() []ComponentLevel {
+	expiries := c.levels.Expiries()
+	var list []ComponentLevel
+	for pattern, level := range c.levels.All() {
+		cl := ComponentLevel{Component: pattern, Level: level.String()}
+		if at, ok := expiries[pattern]; ok {
+			cl.ExpiresAt = &at
+		}
+		list = append(list, cl)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Component < list[j].Component })
+	return list
+}
+
+// Set changes the level of pattern on behalf of who, reverting after ttl
+// unless it is 0.
+func (c *LevelControl) Set(pattern string, level zerolog.Level, ttl time.Duration, who string) error {
+	var err error
+	if ttl > 0 {
+		err = c.levels.SetFor(pattern, level, ttl, func() {
+			c.logger.Info().Str(FieldComponent, pattern).Msg("Log level reverted")
+		})
+	} else {
+		err = c.levels.Set(pattern, level)
+	}
+	if err != nil {
+		return err
+	}
+	e := c.logger.Info().Str(FieldComponent, pattern).Str("level", level.String()).Str("by", who)
+	if ttl > 0 {
+		e = e.Dur("ttl", ttl)
+	}
+	e.Msg("Log level changed")
+	return nil
+}
+
+// Unset removes the level of pattern on behalf of who.
+func (c *LevelControl) Unset(pattern string, who string) {
+	c.levels.Unset(pattern)
+	c.logger.Info().Str(FieldComponent, pattern).Str("by", who).Msg("Log level removed")
+}
+
+type levelRequest struct {
+	Component string `json:"component"`
+	Level     string `json:"level"`
+	TTL       string `json:"ttl"`
+}
+
+func (c *LevelControl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.authenticate == nil {
+		http.Error(w, "no authenticator configured", http.StatusForbidden)
+		return
+	}
+	who, err := c.authenticate(r)
+	if err != nil {
+		c.logger.Warn().Str("remote_addr", r.RemoteAddr).Err(err).Msg("Rejected a log level request")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req levelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := c.setRequest(req, who); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		component := r.URL.Query().Get("component")
+		if component == "" {
+			http.Error(w, "missing component", http.StatusBadRequest)
+			return
+		}
+		c.Unset(component, who)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(c.List())
+}
+
+func (c *LevelControl) setRequest(req levelRequest, who string) error {
+	if req.Component == "" {
+		req.Component = AllComponents
+	}
+	level, err := zerolog.ParseLevel(req.Level)
+	if err != nil || req.Level == "" {
+		return fmt.Errorf("unknown level %q", req.Level)
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid ttl %q", req.TTL)
+		}
+	}
+	return c.Set(req.Component, level, ttl, who)
+}
@@ -0,0 +1,110 @@
+package logger_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/logger"
+	"github.com/case-management-suite/common/logger/logtest"
+	"github.com/rs/zerolog"
+)
+
+func TestLevelControlHTTP(t *testing.T) {
+	l, rec := logtest.NewT(t)
+	levels := logger.NewLevels()
+	auth := logger.BasicAuthenticator(func(user, password string) bool { return user == "ana" && password == "secret" })
+	srv := httptest.NewServer(logger.NewLevelControl(levels, l, auth))
+	defer srv.Close()
+
+	password := "secret"
+	do := func(method, path, body string) (int, []logger.ComponentLevel) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.SetBasicAuth("ana", password)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var list []logger.ComponentLevel
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, list
+	}
+
+	if code, _ := do(http.MethodPut, "/", `{"level": "warn"}`); code != http.StatusOK {
+		t.Fatalf("PUT global = %d", code)
+	}
+	code, list := do(http.MethodPut, "/", `{"component": "cases.db", "level": "debug", "ttl": "1h"}`)
+	if code != http.StatusOK || len(list) != 2 || list[1].Component != "cases.db" || list[1].Level != "debug" || list[1].ExpiresAt == nil {
+		t.Errorf("PUT cases.db = %d %+v", code, list)
+	}
+	if level, _ := levels.Lookup("cases.service"); level != zerolog.WarnLevel {
+		t.Errorf("global level = %s", level)
+	}
+	rec.AssertLogged(t, logtest.Message("Log level changed"), logtest.Field(logger.FieldComponent, "cases.db"), logtest.Field("by", "ana"))
+
+	for _, body := range []string{`{"level": "loud"}`, `{"component": "cases.", "level": "info"}`, `{"level": "info", "ttl": "-1s"}`, `{`} {
+		if code, _ := do(http.MethodPut, "/", body); code != http.StatusBadRequest {
+			t.Errorf("PUT %s = %d, want 400", body, code)
+		}
+	}
+	if code, list := do(http.MethodDelete, "/?component=cases.db", ""); code != http.StatusOK || len(list) != 1 {
+		t.Errorf("DELETE = %d %+v", code, list)
+	}
+	if code, _ := do(http.MethodPost, "/", ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d", code)
+	}
+
+	password = "guess"
+	if code, _ := do(http.MethodPut, "/", `{"level": "trace"}`); code != http.StatusUnauthorized {
+		t.Errorf("PUT with a wrong password = %d, want 401", code)
+	}
+	if level, _ := levels.Lookup(logger.AllComponents); level != zerolog.WarnLevel {
+		t.Errorf("global level = %s after a rejected request", level)
+	}
+	rec.AssertLogged(t, logtest.Message("Rejected a log level request"))
+}
+
+func TestLevelControlWithoutAuthenticator(t *testing.T) {
+	l, _ := logtest.NewT(t)
+	rr := httptest.NewRecorder()
+	logger.NewLevelControl(logger.NewLevels(), l, nil).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("GET = %d, want 403", rr.Code)
+	}
+}
+
+func TestLevelControlTTL(t *testing.T) {
+	l, rec := logtest.NewT(t)
+	levels := logger.NewLevels()
+	control := logger.NewLevelControl(levels, l, nil)
+	if err := control.Set("cases.db", zerolog.InfoLevel, 0, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if err := control.Set("cases.db", zerolog.DebugLevel, time.Hour, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if err := control.Set("cases.db", zerolog.TraceLevel, time.Hour, "ops"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !levels.Revert("cases.db") {
+		t.Fatal("no temporary level to revert")
+	}
+	rec.AssertLogged(t, logtest.Message("Log level reverted"), logtest.Field(logger.FieldComponent, "cases.db"))
+	// Back to the level before the temporary changes.
+	if level, ok := levels.Lookup("cases.db"); !ok || level != zerolog.InfoLevel {
+		t.Errorf("level after revert = %s, %v", level, ok)
+	}
+	if len(levels.Expiries()) != 0 {
+		t.Errorf("expiries left: %v", levels.Expiries())
+	}
+}
@@ -0,0 +1,281 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/rs/zerolog"
+)
+
+// AllComponents is the pattern of the level applied to every component
+// without a more specific one.
+const AllComponents = "*"
+
+// Levels holds the log levels of the components, by dotted name such as
+// "cases.db". A pattern is a component name, a name ending in ".*" matching
+// it and the names below it, e.g. "cases.*", or AllComponents. The most
+// specific pattern wins; components without any follow the level of the
+// logger they derive from.
+type Levels struct {
+	mu       sync.RWMutex
+	patterns map[string]zerolog.Level
+	expiries map[string]*levelExpiry
+	// generation counts the changes of the patterns, for the loggers to
+	// derive their level again.
+	generation atomic.Uint64
+}
+
+// levelExpiry restores the level a pattern had before a temporary change.
+type levelExpiry struct {
+	at       time.Time
+	timer    *time.Timer
+	previous zerolog.Level
+	wasSet   bool
+	onRevert func()
+}
+
+func NewLevels() *Levels {
+	return &Levels{patterns: map[string]zerolog.Level{}, expiries: map[string]*levelExpiry{}}
+}
+
+// DefaultLevels is the registry of the loggers returned by NewServiceLogger
+// and WithComponent.
+var DefaultLevels = NewLevels()
+
+// Set changes the level of the components matching pattern. It takes effect
+// on the loggers already created.
+func (lv *Levels) Set(pattern string, level zerolog.Level) error {
+	if err := checkPattern(pattern); err != nil {
+		return err
+	}
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	lv.cancelExpiry(pattern)
+	lv.patterns[pattern] = level
+	lv.generation.Add(1)
+	return nil
+}
+
+// SetFor is Set until ttl elapses, then the level pattern had before the
+// first of the temporary changes in progress is restored and onRevert, when
+// not nil, is called.
+func (lv *Levels) SetFor(pattern string, level zerolog.Level, ttl time.Duration, onRevert func()) error {
+	if err := checkPattern(pattern); err != nil {
+		return err
+	}
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	e := &levelExpiry{at: time.Now().Add(ttl), onRevert: onRevert}
+	if old, ok := lv.expiries[pattern]; ok {
+		old.timer.Stop()
+		e.previous, e.wasSet = old.previous, old.wasSet
+	} else {
+		e.previous, e.wasSet = lv.patterns[pattern]
+	}
+	e.timer = time.AfterFunc(ttl, func() { lv.revert(pattern, e) })
+	lv.expiries[pattern] = e
+	lv.patterns[pattern] = level
+	lv.generation.Add(1)
+	return nil
+}
+
+// Revert ends the temporary change of pattern now, as if its ttl elapsed,
+// and tells whether there was one.
+func (lv *Levels) Revert(pattern string) bool {
+	lv.mu.RLock()
+	e, ok := lv.expiries[pattern]
+	lv.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	e.timer.Stop()
+	return lv.revert(pattern, e)
+}
+
+// revert restores the level of pattern before e, unless e was replaced.
+func (lv *Levels) revert(pattern string, e *levelExpiry) bool {
+	lv.mu.Lock()
+	if lv.expiries[pattern] != e {
+		lv.mu.Unlock()
+		return false
+	}
+	delete(lv.expiries, pattern)
+	if e.wasSet {
+		lv.patterns[pattern] = e.previous
+	} else {
+		delete(lv.patterns, pattern)
+	}
+	lv.generation.Add(1)
+	lv.mu.Unlock()
+	if e.onRevert != nil {
+		e.onRevert()
+	}
+	return true
+}
+
+// ApplyConfig sets the levels of the Components of cfg.
+func (lv *Levels) ApplyConfig(cfg config.LogConfig) error {
+	names := make([]string, 0, len(cfg.Components))
+	for name := range cfg.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := cfg.Components[name]
+		if err := lv.Set(c.Pattern, c.Level); err != nil {
+			return fmt.Errorf("log component %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Unset removes the level of pattern.
+func (lv *Levels) Unset(pattern string) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	lv.cancelExpiry(pattern)
+	delete(lv.patterns, pattern)
+	lv.generation.Add(1)
+}
+
+// Expiries returns when the temporary levels revert, by pattern.
+func (lv *Levels) Expiries() map[string]time.Time {
+	lv.mu.RLock()
+	defer lv.mu.RUnlock()
+	expiries := make(map[string]time.Time, len(lv.expiries))
+	for p, e := range lv.expiries {
+		expiries[p] = e.at
+	}
+	return expiries
+}
+
+func (lv *Levels) cancelExpiry(pattern string) {
+	if e, ok := lv.expiries[pattern]; ok {
+		e.timer.Stop()
+		delete(lv.expiries, pattern)
+	}
+}
+
+// All returns a copy of the levels by pattern.
+func (lv *Levels) All() map[string]zerolog.Level {
+	lv.mu.RLock()
+	defer lv.mu.RUnlock()
+	all := make(map[string]zerolog.Level, len(lv.patterns))
+	for p, l := range lv.patterns {
+		all[p] = l
+	}
+	return all
+}
+
+// Lookup returns the level of the most specific pattern matching component.
+func (lv *Levels) Lookup(component string) (zerolog.Level, bool) {
+	lv.mu.RLock()
+	defer lv.mu.RUnlock()
+	return lookupPattern(lv.patterns, component)
+}
+
+func lookupPattern[V any](patterns map[string]V, component string) (V, bool) {
+	if v, ok := patterns[component]; ok {
+		return v, true
+	}
+	for name := component; name != ""; {
+		if v, ok := patterns[name+".*"]; ok {
+			return v, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	v, ok := patterns[AllComponents]
+	return v, ok
+}
+
+func checkPattern(pattern string) error {
+	name := strings.TrimSuffix(pattern, ".*")
+	if pattern == AllComponents {
+		return nil
+	}
+	if name == "" || strings.Contains(name, "*") || strings.Contains(name, "..") || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return fmt.Errorf("invalid component pattern %q", pattern)
+	}
+	return nil
+}
+
+// Logger returns a logger for component whose level follows the registry,
+// or the level of l when no pattern matches. The events below the level are
+// not built, see Logger.Enabled.
+func (lv *Levels) Logger(l Logger, component string) Logger {
+	c := &componentLevel{levels: lv, component: component, parent: l}
+	// The sampler filters the levels, so that they can be lowered at runtime.
+	return Logger{Logger: l.Level(zerolog.TraceLevel).Sample(c)}
+}
+
+// componentLevel is the level of a component, derived again from the
+// registry when its patterns change.
+type componentLevel struct {
+	levels    *Levels
+	component string
+	parent    Logger
+	derived   atomic.Pointer[derivedLevel]
+}
+
+type derivedLevel struct {
+	generation uint64
+	level      zerolog.Level
+}
+
+func (c *componentLevel) Sample(level zerolog.Level) bool {
+	return level >= c.level()
+}
+
+func (c *componentLevel) level() zerolog.Level {
+	generation := c.levels.generation.Load()
+	if d := c.derived.Load(); d != nil && d.generation == generation {
+		return d.level
+	}
+	level, ok := c.levels.Lookup(c.component)
+	if !ok {
+		level = c.parent.GetLevel()
+	}
+	c.derived.Store(&derivedLevel{generation: generation, level: level})
+	return level
+}
+
+// Enabled tells whether l logs the events of level.
+func (l Logger) Enabled(level zerolog.Level) bool {
+	e := l.WithLevel(level)
+	if e == nil {
+		return false
+	}
+	e.Discard()
+	return true
+}
+
+// GetLevel returns the minimum level logged by l, the one of its component
+// for the loggers of Levels.
+func (l Logger) GetLevel() zerolog.Level {
+	level := l.Logger.GetLevel()
+	if level > zerolog.PanicLevel {
+		return level
+	}
+	for ; level <= zerolog.PanicLevel; level++ {
+		if l.Enabled(level) {
+			return level
+		}
+	}
+	return zerolog.Disabled
+}
+
+// WithComponent returns a logger for component, tagged with its name and
+// leveled by DefaultLevels.
+func WithComponent(l Logger, component string) Logger {
+	l = Logger{Logger: l.With().Str(FieldComponent, component).Logger()}
+	return DefaultLevels.Logger(l, component)
+}
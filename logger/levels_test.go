@@ -0,0 +1,151 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/logger"
+	"github.com/rs/zerolog"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelsLookup(t *testing.T) {
+	levels := logger.NewLevels()
+	for pattern, level := range map[string]zerolog.Level{
+		"*":            zerolog.WarnLevel,
+		"cases.*":      zerolog.InfoLevel,
+		"cases.db":     zerolog.DebugLevel,
+		"cases.db.*":   zerolog.ErrorLevel,
+		"rules.server": zerolog.TraceLevel,
+	} {
+		if err := levels.Set(pattern, level); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for component, want := range map[string]zerolog.Level{
+		"cases":         zerolog.InfoLevel,
+		"cases.service": zerolog.InfoLevel,
+		"cases.db":      zerolog.DebugLevel,
+		"cases.db.pool": zerolog.ErrorLevel,
+		"rules.server":  zerolog.TraceLevel,
+		"rules.service": zerolog.WarnLevel,
+	} {
+		if got, ok := levels.Lookup(component); !ok || got != want {
+			t.Errorf("Lookup(%s) = %s, %v, want %s", component, got, ok, want)
+		}
+	}
+
+	levels.Unset("*")
+	if _, ok := levels.Lookup("rules.service"); ok {
+		t.Error("expected no level once the global one is unset")
+	}
+	for _, pattern := range []string{"", "cases.", ".*", "ca*es", "cases..db"} {
+		if err := levels.Set(pattern, zerolog.InfoLevel); err == nil {
+			t.Errorf("expected pattern %q to be rejected", pattern)
+		}
+	}
+}
+
+func TestLevelsApplyAtRuntime(t *testing.T) {
+	var buf bytes.Buffer
+	base := logger.Logger{Logger: zerolog.New(&buf).Level(zerolog.InfoLevel)}
+	levels := logger.NewLevels()
+	db := levels.Logger(base, logger.ComponentCasesDB)
+	other := levels.Logger(base, logger.ComponentCasesService)
+
+	db.Debug().Msg("before")
+	if buf.Len() != 0 {
+		t.Fatalf("debug logged at the base level: %s", buf.String())
+	}
+
+	cfg := logger.NewLogConfig(base)
+	cfg.CasesDB = zerolog.DebugLevel
+	cfg.Apply(levels)
+	db.Debug().Msg("query")
+	other.Debug().Msg("hidden")
+	if got := buf.String(); !strings.Contains(got, "query") || strings.Contains(got, "hidden") {
+		t.Errorf("unexpected output %s", got)
+	}
+
+	buf.Reset()
+	if err := levels.Set(logger.AllComponents, zerolog.ErrorLevel); err != nil {
+		t.Fatal(err)
+	}
+	other.Info().Msg("hidden")
+	other.Error().Msg("shown")
+	if got := buf.String(); strings.Contains(got, "hidden") || !strings.Contains(got, "shown") {
+		t.Errorf("unexpected output %s", got)
+	}
+}
+
+func TestLevelsLoggerReportsItsLevel(t *testing.T) {
+	base := logger.Logger{Logger: zerolog.New(io.Discard).Level(zerolog.InfoLevel)}
+	levels := logger.NewLevels()
+	db := levels.Logger(base, logger.ComponentCasesDB)
+	if db.Debug() != nil || db.GetLevel() != zerolog.InfoLevel {
+		t.Errorf("debug enabled below the base level, level %s", db.GetLevel())
+	}
+
+	if err := levels.Set("cases.*", zerolog.DebugLevel); err != nil {
+		t.Fatal(err)
+	}
+	if db.Debug() == nil || db.GetLevel() != zerolog.DebugLevel {
+		t.Errorf("debug disabled after Set, level %s", db.GetLevel())
+	}
+	if !db.Slog().Enabled(context.Background(), slog.LevelDebug) || !db.Zap().Core().Enabled(zapcore.DebugLevel) {
+		t.Error("the bridges do not follow the level of the component")
+	}
+
+	levels.Unset("cases.*")
+	if db.Debug() != nil || db.Slog().Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug enabled after Unset")
+	}
+}
+
+func TestNewLoggerFromConfigAppliesComponents(t *testing.T) {
+	// The components are applied once to DefaultLevels, and stay.
+	appConfig := config.AppConfig{Log: config.LogConfig{
+		Level:      zerolog.InfoLevel,
+		Components: map[string]config.LogComponentLevel{"scheduler": {Pattern: "levels-test.scheduler", Level: zerolog.TraceLevel}},
+	}}
+	if l := logger.NewServiceLoggerFromConfig("levels-test.scheduler", appConfig); l.GetLevel() != zerolog.TraceLevel {
+		t.Errorf("scheduler logs at %s, want trace", l.GetLevel())
+	}
+	if l := logger.NewServiceLoggerFromConfig("levels-test.queue", appConfig); l.GetLevel() != zerolog.InfoLevel {
+		t.Errorf("queue logs at %s, want info", l.GetLevel())
+	}
+}
+
+func TestLogConfigComponents(t *testing.T) {
+	cfg := logger.NewLogConfig(logger.NewTestLogger())
+	if got := cfg.Components(); len(got) != 0 {
+		t.Errorf("unset config has levels %v", got)
+	}
+	cfg.RulesServer = zerolog.WarnLevel
+	if got := cfg.Components(); len(got) != 1 || got[logger.ComponentRulesServer] != zerolog.WarnLevel {
+		t.Errorf("Components = %v", got)
+	}
+}
+
+func TestLevelsSetForRevertsAfterTTL(t *testing.T) {
+	levels := logger.NewLevels()
+	reverted := make(chan struct{})
+	if err := levels.SetFor("cases.db", zerolog.DebugLevel, time.Millisecond, func() { close(reverted) }); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reverted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the level did not revert")
+	}
+	if _, ok := levels.Lookup("cases.db"); ok || levels.Revert("cases.db") {
+		t.Error("the temporary level is still set")
+	}
+}
@@ -1,7 +1,10 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"sync"
 
 	"github.com/case-management-suite/common/config"
 	"github.com/rs/zerolog"
@@ -21,22 +24,154 @@ func BuildDefaultLogger() Logger {
 	return Logger{Logger: log.Output(zerolog.ConsoleWriter{Out: os.Stderr}).With().CallerWithSkipFrameCount(4).Logger()}
 }
 
+// NewLogger returns a logger set up by the profile of env, or logging to
+// stderr after the default redact rules when env has none.
 func NewLogger(env config.EnvType) Logger {
-	switch env {
-	case config.Env.Local, config.Env.Test:
-		return Logger{Logger: log.Output(zerolog.ConsoleWriter{Out: os.Stderr}).With().CallerWithSkipFrameCount(4).Logger()}
-	case config.Env.Prod:
-		return Logger{Logger: log.With().CallerWithSkipFrameCount(4).Logger()}
-	default:
-		log.Warn().Interface("env", env).Msg("The request environment is not recognized")
-		return Logger{Logger: log.Logger}
+	appConfig, err := config.ForEnv(env)
+	if err != nil {
+		l := stderrLogger(config.LogConfig{Redact: config.DefaultRedactRules()})
+		l.Warn().Interface("env", env).Msg("The request environment is not recognized")
+		return l
 	}
+	return NewLoggerFromConfig(appConfig)
 }
 
+// NewLoggerFromConfig returns a logger set up by the Log section of appConfig,
+// whose Components are applied to DefaultLevels for the first logger.
+// The loggers of the same Log section share its sinks, kept open until the
+// holders of HoldSinks release them. It falls back to stderr, still
+// redacting, when a sink cannot be opened.
+func NewLoggerFromConfig(appConfig config.AppConfig) Logger {
+	l, err := sharedLogger(appConfig.Log)
+	if err != nil {
+		l = stderrLogger(appConfig.Log)
+		l.Error().Err(err).Msg("Failed to configure the logger, logging to stderr")
+	}
+	return Logger{Logger: l.With().Str(FieldEnv, string(appConfig.Env)).CallerWithSkipFrameCount(4).Logger()}
+}
+
+// stderrLogger logs to stderr after the Redact rules of cfg, or the default
+// ones when they cannot be applied.
+func stderrLogger(cfg config.LogConfig) Logger {
+	r, err := NewRedactorFromConfig(cfg)
+	if err != nil {
+		r, _ = NewRedactorFromConfig(config.LogConfig{Redact: config.DefaultRedactRules()})
+	}
+	return Logger{Logger: log.Output(r.Writer(zerolog.ConsoleWriter{Out: os.Stderr}))}
+}
+
+// configured holds the logger built by Configure for each Log section, and
+// the sections whose Components were applied to DefaultLevels.
+var configured = struct {
+	sync.Mutex
+	byConfig map[string]*sharedSinks
+	leveled  map[string]bool
+}{byConfig: map[string]*sharedSinks{}, leveled: map[string]bool{}}
+
+type sharedSinks struct {
+	logger Logger
+	closer io.Closer
+	holds  int
+}
+
+// configKey identifies cfg, fmt printing the maps in key order.
+func configKey(cfg config.LogConfig) string {
+	return fmt.Sprintf("%#v", cfg)
+}
+
+func sharedLogger(cfg config.LogConfig) (Logger, error) {
+	configured.Lock()
+	defer configured.Unlock()
+	s, err := lookupSinks(configKey(cfg), cfg)
+	if err != nil {
+		return Logger{}, err
+	}
+	return s.logger, nil
+}
+
+// lookupSinks is called with configured locked.
+func lookupSinks(key string, cfg config.LogConfig) (*sharedSinks, error) {
+	if s, ok := configured.byConfig[key]; ok {
+		return s, nil
+	}
+	// Once only, not to undo the changes made at runtime.
+	if !configured.leveled[key] {
+		if err := DefaultLevels.ApplyConfig(cfg); err != nil {
+			return nil, err
+		}
+		configured.leveled[key] = true
+	}
+	l, closer, err := Configure(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &sharedSinks{logger: l, closer: closer}
+	configured.byConfig[key] = s
+	return s, nil
+}
+
+// HoldSinks keeps the sinks shared by the loggers of cfg open until the
+// returned closer is closed. The sinks are closed when the last holder
+// releases them, and opened again by the next logger of cfg.
+func HoldSinks(cfg config.LogConfig) io.Closer {
+	key := configKey(cfg)
+	configured.Lock()
+	defer configured.Unlock()
+	s, err := lookupSinks(key, cfg)
+	if err != nil {
+		// NewLoggerFromConfig logs to stderr, there is nothing to close.
+		return multiCloser(nil)
+	}
+	s.holds++
+	return &sinksHold{key: key, sinks: s}
+}
+
+type sinksHold struct {
+	key   string
+	sinks *sharedSinks
+	once  sync.Once
+}
+
+func (h *sinksHold) Close() error {
+	var err error
+	h.once.Do(func() {
+		configured.Lock()
+		h.sinks.holds--
+		last := h.sinks.holds == 0
+		if last && configured.byConfig[h.key] == h.sinks {
+			delete(configured.byConfig, h.key)
+		}
+		configured.Unlock()
+		if last {
+			err = h.sinks.closer.Close()
+		}
+	})
+	return err
+}
+
+// NewServiceLogger returns a logger for the service name, leveled by
+// DefaultLevels under that name.
 func NewServiceLogger(name string, env config.EnvType) Logger {
-	return Logger{Logger: NewLogger(env).With().Str("service", name).Logger()}
+	return serviceLogger(NewLogger(env), name)
+}
+
+// NewServiceLoggerFromConfig is NewLoggerFromConfig for the service name.
+func NewServiceLoggerFromConfig(name string, appConfig config.AppConfig) Logger {
+	return serviceLogger(NewLoggerFromConfig(appConfig), name)
 }
 
+func serviceLogger(l Logger, name string) Logger {
+	l = Logger{Logger: l.With().Str(FieldService, name).Logger()}
+	return DefaultLevels.Logger(l, name)
+}
+
+// NewTestLogger logs to stderr with the redaction rules of the test profile.
 func NewTestLogger() Logger {
-	return Logger{Logger: log.Output(zerolog.ConsoleWriter{Out: os.Stderr}).Level(zerolog.DebugLevel).With().CallerWithSkipFrameCount(4).Logger()}
+	var out io.Writer = zerolog.ConsoleWriter{Out: os.Stderr}
+	if appConfig, err := config.ForEnv(config.Env.Test); err == nil {
+		if r, err := NewRedactorFromConfig(appConfig.Log); err == nil {
+			out = r.Writer(out)
+		}
+	}
+	return Logger{Logger: log.Output(out).Level(zerolog.DebugLevel).With().CallerWithSkipFrameCount(4).Logger()}
 }
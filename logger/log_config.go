@@ -1,9 +1,25 @@
 package logger
 
 import (
+	"sync"
+
 	"github.com/rs/zerolog"
 )
 
+// Names of the components leveled by a LogConfig.
+const (
+	ComponentQueueService  = "queue.service"
+	ComponentWorkScheduler = "work.scheduler"
+	ComponentRulesService  = "rules.service"
+	ComponentRulesServer   = "rules.server"
+	ComponentCasesService  = "cases.service"
+	ComponentCasesServer   = "cases.server"
+	ComponentCasesDB       = "cases.db"
+)
+
+// LogConfig sets the level of each component. A component left at
+// zerolog.NoLevel follows the other patterns of the registry. Sampling is
+// keyed by component pattern, as in Levels.
 type LogConfig struct {
 	QueueService  zerolog.Level
 	WorkScheduler zerolog.Level
@@ -13,4 +29,88 @@ type LogConfig struct {
 	CasesServer   zerolog.Level
 	CasesDB       zerolog.Level
 	Logger        Logger
+	Sampling      map[string]SamplingConfig
+	// samplers holds the Sampler of every sampled component.
+	samplers *componentSamplers
+}
+
+type componentSamplers struct {
+	mu     sync.Mutex
+	byName map[string]*Sampler
+}
+
+// NewLogConfig returns a configuration leaving every component unset.
+func NewLogConfig(l Logger) LogConfig {
+	return LogConfig{
+		QueueService:  zerolog.NoLevel,
+		WorkScheduler: zerolog.NoLevel,
+		RulesServce:   zerolog.NoLevel,
+		RulesServer:   zerolog.NoLevel,
+		CasesService:  zerolog.NoLevel,
+		CasesServer:   zerolog.NoLevel,
+		CasesDB:       zerolog.NoLevel,
+		Logger:        l,
+		samplers:      &componentSamplers{byName: map[string]*Sampler{}},
+	}
+}
+
+// Components returns the levels set by the configuration by component name.
+func (c LogConfig) Components() map[string]zerolog.Level {
+	components := map[string]zerolog.Level{}
+	for name, level := range map[string]zerolog.Level{
+		ComponentQueueService:  c.QueueService,
+		ComponentWorkScheduler: c.WorkScheduler,
+		ComponentRulesService:  c.RulesServce,
+		ComponentRulesServer:   c.RulesServer,
+		ComponentCasesService:  c.CasesService,
+		ComponentCasesServer:   c.CasesServer,
+		ComponentCasesDB:       c.CasesDB,
+	} {
+		if level != zerolog.NoLevel {
+			components[name] = level
+		}
+	}
+	return components
+}
+
+// Apply sets the levels of the configuration in levels.
+func (c LogConfig) Apply(levels *Levels) {
+	for name, level := range c.Components() {
+		// The component names are valid patterns.
+		_ = levels.Set(name, level)
+	}
+}
+
+// ComponentLogger returns the logger of the configuration for component,
+// sampled when a pattern of Sampling matches it. The loggers of a component
+// share its Sampler, owned by the configuration until Close. Sampling needs
+// a configuration made by NewLogConfig: the others have no Sampler to
+// share and return unsampled loggers.
+func (c LogConfig) ComponentLogger(component string) Logger {
+	l := WithComponent(c.Logger, component)
+	cfg, ok := lookupPattern(c.Sampling, component)
+	if !ok || c.samplers == nil {
+		return l
+	}
+	c.samplers.mu.Lock()
+	defer c.samplers.mu.Unlock()
+	s, ok := c.samplers.byName[component]
+	if !ok {
+		s = NewSampler(l, cfg)
+		c.samplers.byName[component] = s
+	}
+	return s.Logger()
+}
+
+// Close closes the Samplers of the components.
+func (c LogConfig) Close() {
+	if c.samplers == nil {
+		return
+	}
+	c.samplers.mu.Lock()
+	defer c.samplers.mu.Unlock()
+	for name, s := range c.samplers.byName {
+		s.Close()
+		delete(c.samplers.byName, name)
+	}
 }
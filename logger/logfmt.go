@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// logfmtWriter rewrites the JSON lines of zerolog as key=value pairs, starting
+// with the time, level and message.
+type logfmtWriter struct {
+	Out io.Writer
+}
+
+var logfmtLeadingKeys = []string{zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.MessageFieldName}
+
+func (w logfmtWriter) Write(p []byte) (int, error) {
+	keys, values, err := decodeFields(p)
+	if err != nil {
+		return 0, fmt.Errorf("cannot decode log line: %w", err)
+	}
+
+	var buf bytes.Buffer
+	write := func(key string, value interface{}) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		if key == zerolog.MessageFieldName {
+			key = "msg"
+		}
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(logfmtValue(value))
+	}
+	for _, key := range logfmtLeadingKeys {
+		if value, ok := values[key]; ok {
+			write(key, value)
+		}
+	}
+	for _, key := range keys {
+		if !isLeadingKey(key) {
+			write(key, values[key])
+		}
+	}
+	buf.WriteByte('\n')
+
+	if _, err := w.Out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func isLeadingKey(key string) bool {
+	for _, k := range logfmtLeadingKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeFields returns the fields of a JSON object in their order.
+func decodeFields(p []byte) ([]string, map[string]interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return nil, nil, fmt.Errorf("not a JSON object")
+	}
+	var keys []string
+	values := map[string]interface{}{}
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := t.(string)
+		var value interface{}
+		if err := d.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	return keys, values, nil
+}
+
+func logfmtValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		s = v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, _ := json.Marshal(v)
+		s = string(b)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\\\t\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
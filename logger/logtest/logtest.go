@@ -0,0 +1,212 @@
+// Package logtest captures the lines of a logger.Logger in memory so that
+// tests can assert what was logged.
+package logtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/case-management-suite/common/logger"
+	"github.com/rs/zerolog"
+)
+
+// Entry is a captured log line.
+type Entry struct {
+	Level   zerolog.Level
+	Message string
+	// Fields holds every field of the line as decoded from JSON, so numbers
+	// are float64.
+	Fields map[string]interface{}
+	Raw    string
+}
+
+// Recorder stores the lines written by its Logger. It is safe for concurrent
+// use.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// New returns a recorder and a logger writing to it at every level.
+func New() (logger.Logger, *Recorder) {
+	r := &Recorder{}
+	return r.Logger(), r
+}
+
+// NewT is New, dumping the captured lines to the log of t when the test fails.
+func NewT(t testing.TB) (logger.Logger, *Recorder) {
+	l, r := New()
+	t.Cleanup(func() {
+		if t.Failed() {
+			var b strings.Builder
+			r.Dump(&b)
+			t.Logf("captured logs:\n%s", b.String())
+		}
+	})
+	return l, r
+}
+
+// Logger returns a logger writing to the recorder.
+func (r *Recorder) Logger() logger.Logger {
+	return logger.Logger{Logger: zerolog.New(r).Level(zerolog.TraceLevel).With().Timestamp().Logger()}
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return 0, fmt.Errorf("cannot decode log line: %w", err)
+	}
+	e := Entry{Level: zerolog.NoLevel, Fields: fields, Raw: strings.TrimSuffix(string(p), "\n")}
+	if s, ok := fields[zerolog.LevelFieldName].(string); ok {
+		if level, err := zerolog.ParseLevel(s); err == nil {
+			e.Level = level
+		}
+	}
+	e.Message, _ = fields[zerolog.MessageFieldName].(string)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+	return len(p), nil
+}
+
+// Entries returns the captured lines in order.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Reset forgets the captured lines.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// Filter returns the lines matching every matcher.
+func (r *Recorder) Filter(matchers ...Matcher) []Entry {
+	var found []Entry
+	for _, e := range r.Entries() {
+		if matchAll(e, matchers) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// AssertLogged fails t unless a line matches every matcher, and returns the
+// first one.
+func (r *Recorder) AssertLogged(t testing.TB, matchers ...Matcher) Entry {
+	t.Helper()
+	found := r.Filter(matchers...)
+	if len(found) == 0 {
+		t.Errorf("no log line %s among %d lines", describe(matchers), len(r.Entries()))
+		return Entry{}
+	}
+	return found[0]
+}
+
+// AssertNotLogged fails t when a line matches every matcher.
+func (r *Recorder) AssertNotLogged(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+	if found := r.Filter(matchers...); len(found) > 0 {
+		t.Errorf("unexpected log line %s: %s", describe(matchers), found[0].Raw)
+	}
+}
+
+// Dump writes the captured lines to w.
+func (r *Recorder) Dump(w io.Writer) {
+	for _, e := range r.Entries() {
+		fmt.Fprintln(w, e.Raw)
+	}
+}
+
+// Matcher selects log lines.
+type Matcher struct {
+	desc  string
+	match func(Entry) bool
+}
+
+func (m Matcher) String() string {
+	return m.desc
+}
+
+// Match builds a matcher from a predicate.
+func Match(desc string, match func(Entry) bool) Matcher {
+	return Matcher{desc: desc, match: match}
+}
+
+// Level matches the lines of level.
+func Level(level zerolog.Level) Matcher {
+	return Match("at level "+level.String(), func(e Entry) bool { return e.Level == level })
+}
+
+// AtLeast matches the lines of level or higher.
+func AtLeast(level zerolog.Level) Matcher {
+	return Match("at level "+level.String()+" or higher", func(e Entry) bool {
+		return e.Level >= level && e.Level != zerolog.NoLevel
+	})
+}
+
+// Message matches the lines with the message msg.
+func Message(msg string) Matcher {
+	return Match(fmt.Sprintf("with message %q", msg), func(e Entry) bool { return e.Message == msg })
+}
+
+// MessageContains matches the lines whose message contains s.
+func MessageContains(s string) Matcher {
+	return Match(fmt.Sprintf("with message containing %q", s), func(e Entry) bool { return strings.Contains(e.Message, s) })
+}
+
+// Field matches the lines where key has value, compared as JSON.
+func Field(key string, value interface{}) Matcher {
+	want := normalize(value)
+	return Match(fmt.Sprintf("with %s=%v", key, value), func(e Entry) bool {
+		got, ok := e.Fields[key]
+		return ok && reflect.DeepEqual(got, want)
+	})
+}
+
+// HasField matches the lines with the field key.
+func HasField(key string) Matcher {
+	return Match("with field "+key, func(e Entry) bool {
+		_, ok := e.Fields[key]
+		return ok
+	})
+}
+
+// normalize gives value the types it has once decoded from a log line.
+func normalize(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return value
+	}
+	return v
+}
+
+func matchAll(e Entry, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if !m.match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func describe(matchers []Matcher) string {
+	descs := make([]string, len(matchers))
+	for i, m := range matchers {
+		descs[i] = m.desc
+	}
+	return strings.Join(descs, ", ")
+}
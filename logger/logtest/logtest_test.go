@@ -0,0 +1,69 @@
+package logtest_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/case-management-suite/common/logger/logtest"
+	"github.com/rs/zerolog"
+)
+
+// recordingT records the failures instead of failing the test.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	l, rec := logtest.NewT(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.Debug().Int("attempt", i).Msg("Connecting")
+		}(i)
+	}
+	wg.Wait()
+	l.Error().Err(errors.New("address in use")).Str("server_name", "cases").Msg("Failed to start")
+
+	if n := len(rec.Filter(logtest.Level(zerolog.DebugLevel), logtest.Message("Connecting"))); n != 10 {
+		t.Errorf("%d debug lines, want 10", n)
+	}
+	if n := len(rec.Filter(logtest.Field("attempt", 3))); n != 1 {
+		t.Errorf("%d lines with attempt 3, want 1", n)
+	}
+	e := rec.AssertLogged(t, logtest.AtLeast(zerolog.ErrorLevel), logtest.Message("Failed to start"), logtest.Field("server_name", "cases"))
+	if e.Fields["error"] != "address in use" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	rec.AssertNotLogged(t, logtest.MessageContains("panic"))
+
+	rec.Reset()
+	if len(rec.Entries()) != 0 {
+		t.Error("Reset kept the entries")
+	}
+}
+
+func TestAssertionsFail(t *testing.T) {
+	l, rec := logtest.New()
+	l.Warn().Str("queue", "cases").Msg("Reconnecting")
+
+	ft := &recordingT{TB: t}
+	rec.AssertLogged(ft, logtest.Message("Connected"))
+	rec.AssertNotLogged(ft, logtest.HasField("queue"))
+	if len(ft.errors) != 2 {
+		t.Fatalf("got %d failures, want 2: %q", len(ft.errors), ft.errors)
+	}
+	if want := `no log line with message "Connected" among 1 lines`; ft.errors[0] != want {
+		t.Errorf("failure %q, want %q", ft.errors[0], want)
+	}
+}
@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/rs/zerolog"
+)
+
+const redactedMask = "[REDACTED]"
+
+// Redactor masks the sensitive fields and values of the log lines before
+// they are written. It is safe for concurrent use.
+type Redactor struct {
+	mu       sync.RWMutex
+	keys     map[string]bool
+	patterns []redactPattern
+	hashKey  []byte
+}
+
+type redactPattern struct {
+	re   *regexp.Regexp
+	hash bool
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{keys: map[string]bool{}}
+}
+
+// NewRedactorFromConfig returns a redactor applying the Redact rules of cfg,
+// by name order, and hashing with its RedactKey.
+func NewRedactorFromConfig(cfg config.LogConfig) (*Redactor, error) {
+	names := make([]string, 0, len(cfg.Redact))
+	for name := range cfg.Redact {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	r := NewRedactor()
+	r.SetHashKey([]byte(cfg.RedactKey))
+	for _, name := range names {
+		rule := cfg.Redact[name]
+		if rule.Hash && cfg.RedactKey == "" {
+			return nil, fmt.Errorf("redact rule %s: hash without a RedactKey", name)
+		}
+		if rule.Key != "" {
+			r.AddKey(rule.Key, rule.Hash)
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("redact rule %s: %w", name, err)
+			}
+			r.AddPattern(re, rule.Hash)
+		}
+	}
+	return r, nil
+}
+
+// SetHashKey sets the key of the HMAC of the values hashed. Without a key,
+// they are masked.
+func (r *Redactor) SetHashKey(key []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hashKey = append([]byte(nil), key...)
+}
+
+// AddKey masks, or hashes, the values of the fields named key at any depth.
+func (r *Redactor) AddKey(key string, hash bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[strings.ToLower(key)] = hash
+}
+
+// AddPattern masks, or hashes, the matches of re in the string values.
+func (r *Redactor) AddPattern(re *regexp.Regexp, hash bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns = append(r.patterns, redactPattern{re: re, hash: hash})
+}
+
+// Writer returns a writer redacting the JSON lines of zerolog before passing
+// them to w.
+func (r *Redactor) Writer(w io.Writer) zerolog.LevelWriter {
+	return redactWriter{r: r, out: w}
+}
+
+// Redact returns the JSON line p with its sensitive parts masked.
+func (r *Redactor) Redact(p []byte) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.keys) == 0 && len(r.patterns) == 0 {
+		return p, nil
+	}
+	keys, values, err := decodeFields(p)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode log line: %w", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := enc.Encode(key); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(':')
+		if err := enc.Encode(r.redactField(key, values[key])); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+func (r *Redactor) redactField(key string, value interface{}) interface{} {
+	if hash, ok := r.keys[strings.ToLower(key)]; ok {
+		return r.mask(fmt.Sprint(value), hash)
+	}
+	return r.redactValue(value)
+}
+
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		for _, p := range r.patterns {
+			v = p.re.ReplaceAllStringFunc(v, func(s string) string { return r.mask(s, p.hash) })
+		}
+		return v
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = r.redactField(k, e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = r.redactValue(e)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+func (r *Redactor) mask(s string, hash bool) string {
+	if !hash || len(r.hashKey) == 0 {
+		return redactedMask
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(s))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+type redactWriter struct {
+	r   *Redactor
+	out io.Writer
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w redactWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	redacted, err := w.r.Redact(p)
+	if err != nil {
+		// The line may hold anything, only its level is kept.
+		redacted = undecodedLine(level)
+	}
+	if lw, ok := w.out.(zerolog.LevelWriter); ok {
+		_, err = lw.WriteLevel(level, redacted)
+	} else {
+		_, err = w.out.Write(redacted)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// undecodedLine replaces the lines of the level that cannot be redacted.
+func undecodedLine(level zerolog.Level) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	if level != zerolog.NoLevel {
+		fmt.Fprintf(&buf, "%q:%q,", zerolog.LevelFieldName, zerolog.LevelFieldMarshalFunc(level))
+	}
+	fmt.Fprintf(&buf, "%q:%q}\n", zerolog.MessageFieldName, redactedMask+" undecodable log line")
+	return buf.Bytes()
+}
@@ -0,0 +1,138 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/logger"
+	"github.com/rs/zerolog"
+)
+
+type casePayload struct {
+	Applicant struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	} `json:"applicant"`
+	Notes []string `json:"notes"`
+}
+
+func TestRedactorFromProfile(t *testing.T) {
+	appConfig := config.NewLocalAppConfig()
+	r, err := logger.NewRedactorFromConfig(appConfig.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	l := zerolog.New(r.Writer(&buf))
+
+	var payload casePayload
+	payload.Applicant.Name = "Ana"
+	payload.Applicant.Email = "ana@example.com"
+	payload.Applicant.Password = "hunter2"
+	payload.Notes = []string{"SSN 123-45-6789 checked"}
+	l.Info().Str("Authorization", "Bearer abc").Interface("case", payload).Msg("Case created by ana@example.com")
+
+	out := buf.String()
+	for _, secret := range []string{"ana@example.com", "hunter2", "Bearer abc", "123-45-6789"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%q leaked in %s", secret, out)
+		}
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["message"] != "Case created by [REDACTED]" || fields["Authorization"] != "[REDACTED]" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if !strings.HasPrefix(out, `{"level":"info","Authorization"`) || !strings.Contains(out, `"name":"Ana"`) {
+		t.Errorf("fields reordered or lost: %s", out)
+	}
+}
+
+func TestRedactorHash(t *testing.T) {
+	redactor := func(key string) *logger.Redactor {
+		r := logger.NewRedactor()
+		r.SetHashKey([]byte(key))
+		r.AddKey("user", true)
+		r.AddPattern(regexp.MustCompile(`tok_[a-z0-9]+`), true)
+		return r
+	}
+	line := []byte(`{"user":"ana","message":"using tok_abc1"}`)
+	r := redactor("secret")
+	first, err := r.Redact(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := r.Redact(line)
+	if !bytes.Equal(first, second) || bytes.Contains(first, []byte("ana")) || !bytes.Contains(first, []byte(`"using hmac:`)) {
+		t.Errorf("unexpected redaction %s", first)
+	}
+	if other, _ := redactor("other").Redact(line); bytes.Equal(first, other) {
+		t.Errorf("the hashes do not depend on the key: %s", other)
+	}
+	if masked, _ := redactor("").Redact(line); !bytes.Equal(masked, []byte(`{"user":"[REDACTED]","message":"using [REDACTED]"}`+"\n")) {
+		t.Errorf("unexpected redaction without key %s", masked)
+	}
+}
+
+func TestRedactorMasksUndecodableLines(t *testing.T) {
+	r := logger.NewRedactor()
+	r.AddKey("password", false)
+	var buf bytes.Buffer
+	w := r.Writer(&buf)
+	if _, err := w.WriteLevel(zerolog.WarnLevel, []byte(`{"password":"hunter2"`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != `{"level":"warn","message":"[REDACTED] undecodable log line"}`+"\n" {
+		t.Errorf("unexpected line %q", got)
+	}
+}
+
+func TestFallbackLoggerRedacts(t *testing.T) {
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = write
+	defer func() { os.Stderr = stderr }()
+
+	l := logger.NewLoggerFromConfig(config.AppConfig{Log: config.LogConfig{
+		Sinks:  map[string]config.LogSinkConfig{"out": {Type: config.SinkFile, Path: t.TempDir()}},
+		Redact: map[string]config.LogRedactRule{"password": {Key: "password"}},
+	}})
+	l.Info().Str("password", "hunter2").Msg("Logged in")
+	write.Close()
+	out, err := io.ReadAll(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "Logged in") || strings.Contains(string(out), "hunter2") {
+		t.Errorf("unexpected stderr %s", out)
+	}
+}
+
+func TestConfigureRedacts(t *testing.T) {
+	path := t.TempDir() + "/cases.log"
+	l, closer, err := logger.Configure(config.LogConfig{
+		Format: config.LogLogfmt,
+		Sinks:  map[string]config.LogSinkConfig{"file": {Type: config.SinkFile, Path: path}},
+		Redact: map[string]config.LogRedactRule{"token": {Key: "token"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info().Str("token", "s3cr3t").Msg("Signed in")
+	closer.Close()
+	if lines := readLines(t, path); len(lines) != 1 || !strings.Contains(lines[0], "token=[REDACTED]") {
+		t.Errorf("unexpected lines %q", lines)
+	}
+}
@@ -0,0 +1,309 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat stamps the rotated files, e.g. rules-2023-01-02T15-04-05.000.log.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFile appends to the file at Path and moves it aside when it exceeds
+// MaxSize bytes or at every multiple of Every. The zero limits disable the
+// rotation or the removal of backups. It is safe for concurrent use.
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64
+	Every      time.Duration
+	MaxBackups int
+	MaxAge     time.Duration
+	// Compress gzips the rotated files.
+	Compress bool
+	// Clock returns the time of the writes, time.Now when nil.
+	Clock func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time
+	cleaning sync.WaitGroup
+	cleanMu  sync.Mutex
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.due(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate moves the current file aside, even below the limits.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	return r.rotate()
+}
+
+// Close closes the file and waits for the removal and compression of the
+// backups.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+	r.cleaning.Wait()
+	return err
+}
+
+func (r *RotatingFile) now() time.Time {
+	if r.Clock != nil {
+		return r.Clock()
+	}
+	return time.Now()
+}
+
+func (r *RotatingFile) due(n int64) bool {
+	if r.MaxSize > 0 && r.size > 0 && r.size+n > r.MaxSize {
+		return true
+	}
+	return r.Every > 0 && !r.now().Before(r.rotateAt)
+}
+
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if info.IsDir() {
+		f.Close()
+		return fmt.Errorf("%s is a directory", r.Path)
+	}
+	r.file, r.size = f, info.Size()
+	started := r.now()
+	if info.Size() > 0 {
+		// The file was last written in its period or a later one.
+		started = info.ModTime()
+	}
+	r.scheduleRotation(started)
+	return nil
+}
+
+func (r *RotatingFile) scheduleRotation(started time.Time) {
+	if r.Every > 0 {
+		r.rotateAt = started.Truncate(r.Every).Add(r.Every)
+	}
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	now := r.now()
+	if err := os.Rename(r.Path, r.backupName(now)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	r.file, r.size = f, 0
+	r.scheduleRotation(now)
+
+	r.cleaning.Add(1)
+	go func() {
+		defer r.cleaning.Done()
+		r.cleanUp(now)
+	}()
+	return nil
+}
+
+// backupName stamps the backup with t, or the next free millisecond.
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, name := filepath.Split(r.Path)
+	ext := filepath.Ext(name)
+	for ; ; t = t.Add(time.Millisecond) {
+		path := filepath.Join(dir, strings.TrimSuffix(name, ext)+"-"+t.UTC().Format(backupTimeFormat)+ext)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if _, err := os.Stat(path + ".gz"); os.IsNotExist(err) {
+				return path
+			}
+		}
+	}
+}
+
+type backup struct {
+	path string
+	time time.Time
+}
+
+// backups returns the rotated files of Path, newest first.
+func (r *RotatingFile) backups() ([]backup, error) {
+	dir, name := filepath.Split(r.Path)
+	if dir == "" {
+		dir = "."
+	}
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backup
+	for _, e := range entries {
+		stamp := strings.TrimSuffix(e.Name(), ".gz")
+		if e.IsDir() || !strings.HasPrefix(stamp, prefix) || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(stamp, prefix), ext))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, e.Name()), time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].time.After(backups[j].time) })
+	return backups, nil
+}
+
+// cleanUp removes the backups past the retention policy and compresses the
+// others, one clean-up at a time.
+func (r *RotatingFile) cleanUp(now time.Time) {
+	r.cleanMu.Lock()
+	defer r.cleanMu.Unlock()
+	backups, err := r.backups()
+	if err != nil {
+		return
+	}
+	for i, b := range backups {
+		if (r.MaxBackups > 0 && i >= r.MaxBackups) || (r.MaxAge > 0 && now.Sub(b.time) > r.MaxAge) {
+			_ = os.Remove(b.path)
+		} else if r.Compress && !strings.HasSuffix(b.path, ".gz") {
+			_ = compressFile(b.path)
+		}
+	}
+}
+
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// sharedFiles holds the RotatingFile of every path of the file sinks, so that
+// the sinks writing to a path share its size and rotation.
+var sharedFiles = struct {
+	sync.Mutex
+	byPath map[string]*sharedFile
+}{byPath: map[string]*sharedFile{}}
+
+type sharedFile struct {
+	file *RotatingFile
+	refs int
+}
+
+// openSharedFile returns the RotatingFile of the path of f, f itself when the
+// path is not open yet, and a closer releasing it.
+func openSharedFile(f *RotatingFile) (*RotatingFile, io.Closer, error) {
+	path, err := filepath.Abs(f.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedFiles.Lock()
+	defer sharedFiles.Unlock()
+	if s, ok := sharedFiles.byPath[path]; ok {
+		o := s.file
+		if o.MaxSize != f.MaxSize || o.Every != f.Every || o.MaxBackups != f.MaxBackups || o.MaxAge != f.MaxAge || o.Compress != f.Compress {
+			return nil, nil, fmt.Errorf("%s is already written with other rotation settings", f.Path)
+		}
+		s.refs++
+		return o, &sharedFileRef{path: path, shared: s}, nil
+	}
+	// Opens the file now to report the errors here.
+	if _, err := f.Write(nil); err != nil {
+		return nil, nil, err
+	}
+	s := &sharedFile{file: f, refs: 1}
+	sharedFiles.byPath[path] = s
+	return f, &sharedFileRef{path: path, shared: s}, nil
+}
+
+type sharedFileRef struct {
+	path   string
+	shared *sharedFile
+	once   sync.Once
+}
+
+// Close closes the file once every sink writing to it is closed.
+func (r *sharedFileRef) Close() error {
+	var err error
+	r.once.Do(func() {
+		sharedFiles.Lock()
+		r.shared.refs--
+		last := r.shared.refs == 0
+		if last {
+			delete(sharedFiles.byPath, r.path)
+		}
+		sharedFiles.Unlock()
+		if last {
+			err = r.shared.file.Close()
+		}
+	})
+	return err
+}
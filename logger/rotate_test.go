@@ -0,0 +1,179 @@
+package logger_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/logger"
+	"github.com/rs/zerolog"
+)
+
+func backups(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Name() != "rules.log" {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFileBySize(t *testing.T) {
+	dir := t.TempDir()
+	f := &logger.RotatingFile{Path: filepath.Join(dir, "rules.log"), MaxSize: 10, MaxBackups: 2, Compress: true}
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "rules.log")); string(data) != "line 4\n" {
+		t.Errorf("current file has %q", data)
+	}
+	names := backups(t, dir)
+	if len(names) != 2 {
+		t.Fatalf("backups %v, want the 2 newest", names)
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, "rules-") || !strings.HasSuffix(name, ".log.gz") {
+			t.Errorf("unexpected backup %s", name)
+		}
+	}
+	newest, err := os.Open(filepath.Join(dir, names[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer newest.Close()
+	zr, err := gzip.NewReader(newest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != "line 3\n" {
+		t.Errorf("newest backup has %q", data)
+	}
+}
+
+func TestRotatingFileByTime(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	f := &logger.RotatingFile{Path: filepath.Join(dir, "rules.log"), Every: time.Hour, Clock: func() time.Time { return now }}
+	defer f.Close()
+	if _, err := f.Write([]byte("before\n")); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(30 * time.Minute)
+	if _, err := f.Write([]byte("same hour\n")); err != nil {
+		t.Fatal(err)
+	}
+	if names := backups(t, dir); len(names) != 0 {
+		t.Errorf("backups %v before the hour", names)
+	}
+	now = now.Add(30 * time.Minute)
+	if _, err := f.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+	if names := backups(t, dir); len(names) != 1 || names[0] != "rules-2023-01-02T16-04-05.000.log" {
+		t.Errorf("backups %v, want 1 of 16:04", names)
+	}
+}
+
+func TestRotatingFileConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	f := &logger.RotatingFile{Path: filepath.Join(dir, "rules.log"), MaxSize: 100}
+	line := strings.Repeat("x", 9) + "\n"
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := f.Write([]byte(line)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for _, name := range append(backups(t, dir), "rules.log") {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 100 {
+			t.Errorf("%s has %d bytes", name, len(data))
+		}
+		total += strings.Count(string(data), line)
+	}
+	if total != 400 {
+		t.Errorf("%d lines written, want 400", total)
+	}
+}
+
+func TestFileSinksShareTheRotationOfAPath(t *testing.T) {
+	dir := t.TempDir()
+	sink := config.LogSinkConfig{Type: config.SinkFile, Path: filepath.Join(dir, "rules.log"), MaxSizeMB: 1}
+	var loggers []logger.Logger
+	for _, level := range []zerolog.Level{zerolog.InfoLevel, zerolog.DebugLevel} {
+		l, closer, err := logger.Configure(config.LogConfig{Format: config.LogJSON, Level: level, Sinks: map[string]config.LogSinkConfig{"file": sink}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closer.Close()
+		loggers = append(loggers, l)
+	}
+	// Each logger writes less than the limit, both together more.
+	text := strings.Repeat("x", 1000)
+	for i := 0; i < 700; i++ {
+		for _, l := range loggers {
+			l.Info().Msg(text)
+		}
+	}
+
+	names := append(backups(t, dir), "rules.log")
+	if len(names) != 2 {
+		t.Fatalf("files %v, want one backup", names)
+	}
+	total := 0
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 1<<20 {
+			t.Errorf("%s has %d bytes, over the limit", name, len(data))
+		}
+		total += strings.Count(string(data), text)
+	}
+	if total != 1400 {
+		t.Errorf("%d lines written, want 1400", total)
+	}
+
+	other := sink
+	other.MaxBackups = 3
+	if _, _, err := logger.Configure(config.LogConfig{Sinks: map[string]config.LogSinkConfig{"file": other}}); err == nil {
+		t.Error("expected the other rotation settings of the path to be rejected")
+	}
+}
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SamplingConfig thins out repeated messages. Within every Interval a
+// message logs its First occurrences, then one in Thereafter, counted by
+// level and message. Rate then caps what is left at Rate events per second
+// with bursts of Burst. Errors and higher are never dropped unless
+// SampleErrors is set.
+type SamplingConfig struct {
+	Interval     time.Duration
+	First        int
+	Thereafter   int
+	Rate         float64
+	Burst        int
+	SampleErrors bool
+}
+
+const defaultSamplingInterval = time.Second
+
+// Sampler drops the events of a logger according to a SamplingConfig and
+// reports the drops with a warning once per interval, until it is closed.
+type Sampler struct {
+	cfg     SamplingConfig
+	summary Logger
+	logger  Logger
+	stop    chan struct{}
+	stopped chan struct{}
+	close   sync.Once
+
+	mu       sync.Mutex
+	counts   map[sampleKey]*sampleCount
+	tokens   float64
+	refilled time.Time
+	dropped  map[string]int
+}
+
+type sampleKey struct {
+	level zerolog.Level
+	msg   string
+}
+
+type sampleCount struct {
+	n     int
+	start time.Time
+}
+
+// NewSampler samples the events of l, which also logs the summaries. Close
+// stops the summaries.
+func NewSampler(l Logger, cfg SamplingConfig) *Sampler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultSamplingInterval
+	}
+	if cfg.Rate > 0 && cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	s := &Sampler{
+		cfg:     cfg,
+		summary: l,
+		counts:  map[sampleKey]*sampleCount{},
+		tokens:  float64(cfg.Burst),
+		dropped: map[string]int{},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	s.logger = Logger{Logger: l.Hook(samplerHook{s})}
+	go s.reportEvery(cfg.Interval)
+	return s
+}
+
+func (s *Sampler) reportEvery(interval time.Duration) {
+	defer close(s.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Logger returns the sampled logger.
+func (s *Sampler) Logger() Logger {
+	return s.logger
+}
+
+// Flush reports the drops not reported yet.
+func (s *Sampler) Flush() {
+	s.mu.Lock()
+	dropped := s.takeDropped(time.Now())
+	s.mu.Unlock()
+	s.report(dropped)
+}
+
+// Close stops the periodic summaries and reports the last drops.
+func (s *Sampler) Close() {
+	s.close.Do(func() {
+		close(s.stop)
+		<-s.stopped
+		s.Flush()
+	})
+}
+
+type samplerHook struct {
+	s *Sampler
+}
+
+func (h samplerHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level == zerolog.Disabled || (level >= zerolog.ErrorLevel && !h.s.cfg.SampleErrors) {
+		return
+	}
+	now := time.Now()
+	h.s.mu.Lock()
+	keep := h.s.keep(sampleKey{level: level, msg: msg}, now)
+	if !keep {
+		h.s.dropped[msg]++
+	}
+	h.s.mu.Unlock()
+
+	if !keep {
+		e.Discard()
+	}
+}
+
+func (s *Sampler) keep(key sampleKey, now time.Time) bool {
+	if s.cfg.First > 0 || s.cfg.Thereafter > 0 {
+		c, ok := s.counts[key]
+		if !ok || now.Sub(c.start) >= s.cfg.Interval {
+			c = &sampleCount{start: now}
+			s.counts[key] = c
+		}
+		c.n++
+		if c.n > s.cfg.First && (s.cfg.Thereafter <= 0 || (c.n-s.cfg.First)%s.cfg.Thereafter != 0) {
+			return false
+		}
+	}
+	if s.cfg.Rate > 0 {
+		if !s.refilled.IsZero() {
+			s.tokens += now.Sub(s.refilled).Seconds() * s.cfg.Rate
+			if s.tokens > float64(s.cfg.Burst) {
+				s.tokens = float64(s.cfg.Burst)
+			}
+		}
+		s.refilled = now
+		if s.tokens < 1 {
+			return false
+		}
+		s.tokens--
+	}
+	return true
+}
+
+func (s *Sampler) takeDropped(now time.Time) map[string]int {
+	for key, c := range s.counts {
+		if now.Sub(c.start) >= s.cfg.Interval {
+			delete(s.counts, key)
+		}
+	}
+	if len(s.dropped) == 0 {
+		return nil
+	}
+	dropped := s.dropped
+	s.dropped = map[string]int{}
+	return dropped
+}
+
+func (s *Sampler) report(dropped map[string]int) {
+	if len(dropped) == 0 {
+		return
+	}
+	total := 0
+	for _, n := range dropped {
+		total += n
+	}
+	s.summary.Warn().Int("dropped", total).Interface("dropped_by_message", dropped).Msg("Dropped sampled log messages")
+}
@@ -0,0 +1,152 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/logger"
+	"github.com/rs/zerolog"
+	"go.uber.org/goleak"
+)
+
+func countMessages(t *testing.T, buf *bytes.Buffer) map[string]int {
+	t.Helper()
+	counts := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatal(err)
+		}
+		counts[fields["message"].(string)]++
+	}
+	return counts
+}
+
+func TestSamplerFirstThenEvery(t *testing.T) {
+	var buf bytes.Buffer
+	s := logger.NewSampler(logger.Logger{Logger: zerolog.New(&buf)}, logger.SamplingConfig{Interval: time.Hour, First: 3, Thereafter: 10})
+	defer s.Close()
+	l := s.Logger()
+	for i := 0; i < 50; i++ {
+		l.Warn().Msg("Connection lost")
+		l.Info().Msg("Reconnecting")
+		l.Error().Msg("Failed to publish")
+	}
+	s.Flush()
+
+	counts := countMessages(t, &buf)
+	// 3 first, then the 13th, 23rd, 33rd and 43rd.
+	if counts["Connection lost"] != 7 || counts["Reconnecting"] != 7 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+	if counts["Failed to publish"] != 50 {
+		t.Errorf("errors were sampled: %v", counts)
+	}
+	if counts["Dropped sampled log messages"] != 1 || !strings.Contains(buf.String(), `"dropped":86`) {
+		t.Errorf("missing summary in %s", buf.String())
+	}
+}
+
+func TestSamplerRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	s := logger.NewSampler(logger.Logger{Logger: zerolog.New(&buf)}, logger.SamplingConfig{Interval: time.Hour, Rate: 1, Burst: 5, SampleErrors: true})
+	defer s.Close()
+	l := s.Logger()
+	for i := 0; i < 20; i++ {
+		l.Error().Int("i", i).Msg("Failed to publish")
+	}
+	if counts := countMessages(t, &buf); counts["Failed to publish"] != 5 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+// lineWriter passes the lines written on.
+type lineWriter chan string
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestSamplerReportsPeriodically(t *testing.T) {
+	lines := make(lineWriter, 10)
+	s := logger.NewSampler(logger.Logger{Logger: zerolog.New(lines)}, logger.SamplingConfig{Interval: 10 * time.Millisecond, First: 1})
+	defer s.Close()
+	l := s.Logger()
+	for i := 0; i < 3; i++ {
+		l.Warn().Msg("Connection lost")
+	}
+	if line := <-lines; !strings.Contains(line, `"message":"Connection lost"`) {
+		t.Fatalf("unexpected first line %s", line)
+	}
+	// No event follows the drops, the ticker reports them.
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, `"dropped":2`) {
+			t.Errorf("unexpected summary %s", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the drops were not reported")
+	}
+}
+
+func TestSamplerReportsOnClose(t *testing.T) {
+	var buf bytes.Buffer
+	s := logger.NewSampler(logger.Logger{Logger: zerolog.New(&buf)}, logger.SamplingConfig{Interval: time.Hour, First: 1})
+	l := s.Logger()
+	for i := 0; i < 3; i++ {
+		l.Warn().Msg("Connection lost")
+	}
+	s.Close()
+	s.Close()
+	if counts := countMessages(t, &buf); counts["Connection lost"] != 1 || counts["Dropped sampled log messages"] != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestLogConfigSampling(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := logger.NewLogConfig(logger.Logger{Logger: zerolog.New(&buf)})
+	cfg.Sampling = map[string]logger.SamplingConfig{"rules.*": {Interval: time.Hour, First: 1}}
+
+	defer cfg.Close()
+
+	rules := cfg.ComponentLogger(logger.ComponentRulesServer)
+	cases := cfg.ComponentLogger(logger.ComponentCasesServer)
+	for i := 0; i < 5; i++ {
+		rules.Warn().Msg("rules")
+		cases.Warn().Msg("cases")
+		// The loggers of a component share its sampler.
+		again := cfg.ComponentLogger(logger.ComponentRulesServer)
+		again.Warn().Msg("rules")
+	}
+	if counts := countMessages(t, &buf); counts["rules"] != 1 || counts["cases"] != 5 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestComponentLoggersDoNotLeakSamplers(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	sampling := map[string]logger.SamplingConfig{"rules.*": {Interval: time.Hour, First: 1}}
+	literal := logger.LogConfig{Logger: logger.Logger{Logger: zerolog.New(io.Discard)}, Sampling: sampling}
+	for i := 0; i < 100; i++ {
+		l := literal.ComponentLogger(logger.ComponentRulesServer)
+		l.Warn().Msg("rules")
+	}
+
+	cfg := logger.NewLogConfig(logger.Logger{Logger: zerolog.New(io.Discard)})
+	cfg.Sampling = sampling
+	for i := 0; i < 100; i++ {
+		l := cfg.ComponentLogger(logger.ComponentRulesServer)
+		l.Warn().Msg("rules")
+	}
+	cfg.Close()
+}
@@ -0,0 +1,200 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/rs/zerolog"
+)
+
+// Configure builds a logger writing to the sinks of cfg, or to stderr when it
+// has none, after the Redact rules. Close releases the files and connections
+// of the sinks.
+func Configure(cfg config.LogConfig) (Logger, io.Closer, error) {
+	var writers []io.Writer
+	var closers multiCloser
+	names := make([]string, 0, len(cfg.Sinks))
+	for name := range cfg.Sinks {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		writers = append(writers, newFormatWriter(os.Stderr, cfg.Format, false))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w, c, err := newSink(cfg.Sinks[name], cfg.Format)
+		if err != nil {
+			closers.Close()
+			return Logger{}, nil, fmt.Errorf("log sink %s: %w", name, err)
+		}
+		writers = append(writers, w)
+		if c != nil {
+			closers = append(closers, c)
+		}
+	}
+
+	var out io.Writer = writers[0]
+	if len(writers) > 1 {
+		out = zerolog.MultiLevelWriter(writers...)
+	}
+	if len(cfg.Redact) > 0 {
+		r, err := NewRedactorFromConfig(cfg)
+		if err != nil {
+			closers.Close()
+			return Logger{}, nil, err
+		}
+		out = r.Writer(out)
+	}
+	ctx := zerolog.New(out).Level(cfg.Level).With()
+	if cfg.TimeFormat == "" {
+		ctx = ctx.Timestamp()
+	}
+	l := ctx.Logger()
+	if cfg.TimeFormat != "" {
+		l = l.Hook(timestampHook(cfg.TimeFormat))
+	}
+	return Logger{Logger: l}, closers, nil
+}
+
+// timestampHook adds the time in layout, which zerolog only supports globally.
+type timestampHook string
+
+func (h timestampHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	e.Str(zerolog.TimestampFieldName, time.Now().Format(string(h)))
+}
+
+func newSink(cfg config.LogSinkConfig, format config.LogFormat) (io.Writer, io.Closer, error) {
+	if cfg.Format != "" {
+		format = cfg.Format
+	}
+	switch cfg.Type {
+	case config.SinkStderr:
+		return levelFilter{min: cfg.Level, out: newFormatWriter(os.Stderr, format, false)}, nil, nil
+	case config.SinkStdout:
+		return levelFilter{min: cfg.Level, out: newFormatWriter(os.Stdout, format, false)}, nil, nil
+	case config.SinkFile:
+		f, closer, err := openSharedFile(&RotatingFile{
+			Path:       cfg.Path,
+			MaxSize:    int64(cfg.MaxSizeMB) << 20,
+			Every:      cfg.RotateEvery,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			Compress:   cfg.Compress,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return levelFilter{min: cfg.Level, out: newFormatWriter(f, format, true)}, closer, nil
+	case config.SinkUDP:
+		conn, err := net.Dial("udp", cfg.Address)
+		if err != nil {
+			return nil, nil, err
+		}
+		return levelFilter{min: cfg.Level, out: newSyslogWriter(conn, format)}, conn, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown log sink type %q", cfg.Type)
+	}
+}
+
+func newFormatWriter(w io.Writer, format config.LogFormat, noColor bool) io.Writer {
+	switch format {
+	case config.LogConsole:
+		return zerolog.ConsoleWriter{Out: w, NoColor: noColor}
+	case config.LogLogfmt:
+		return logfmtWriter{Out: w}
+	default:
+		return w
+	}
+}
+
+// levelFilter drops the lines below min.
+type levelFilter struct {
+	min zerolog.Level
+	out io.Writer
+}
+
+func (f levelFilter) Write(p []byte) (int, error) {
+	return f.out.Write(p)
+}
+
+func (f levelFilter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < f.min {
+		return len(p), nil
+	}
+	if lw, ok := f.out.(zerolog.LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+	return f.out.Write(p)
+}
+
+// syslogFacility is local0, the first facility left to applications.
+const syslogFacility = 16
+
+// syslogWriter sends every line as an RFC 3164 datagram.
+type syslogWriter struct {
+	conn     net.Conn
+	format   config.LogFormat
+	hostname string
+	tag      string
+}
+
+func newSyslogWriter(conn net.Conn, format config.LogFormat) *syslogWriter {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	return &syslogWriter{conn: conn, format: format, hostname: hostname, tag: filepath.Base(os.Args[0])}
+}
+
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *syslogWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>%s %s %s: ", syslogFacility*8+syslogSeverity(level), time.Now().Format(time.Stamp), w.hostname, w.tag)
+	if _, err := newFormatWriter(&buf, w.format, true).Write(p); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(bytes.TrimRight(buf.Bytes(), "\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func syslogSeverity(level zerolog.Level) int {
+	switch level {
+	case zerolog.PanicLevel:
+		return 0
+	case zerolog.FatalLevel:
+		return 2
+	case zerolog.ErrorLevel:
+		return 3
+	case zerolog.WarnLevel:
+		return 4
+	case zerolog.InfoLevel, zerolog.NoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+type multiCloser []io.Closer
+
+// Close closes every closer and returns the first error.
+func (m multiCloser) Close() error {
+	var first error
+	for _, c := range m {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
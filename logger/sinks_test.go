@@ -0,0 +1,173 @@
+package logger_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/logger"
+	"github.com/rs/zerolog"
+	"go.uber.org/fx"
+)
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestConfigureSinks(t *testing.T) {
+	dir := t.TempDir()
+	all := filepath.Join(dir, "all.log")
+	errors := filepath.Join(dir, "logs", "errors.log")
+	l, closer, err := logger.Configure(config.LogConfig{
+		Format:     config.LogJSON,
+		Level:      zerolog.DebugLevel,
+		TimeFormat: time.RFC3339Nano,
+		Sinks: map[string]config.LogSinkConfig{
+			"all":    {Type: config.SinkFile, Path: all},
+			"errors": {Type: config.SinkFile, Path: errors, Level: zerolog.ErrorLevel, Format: config.LogLogfmt},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Trace().Msg("dropped")
+	l.Info().Str(logger.FieldService, "cases").Msg("Starting server")
+	traced := l.WithTraceID("abc")
+	traced.Error().Msg("Failed to start")
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := readLines(t, all)
+	if len(lines) != 2 {
+		t.Fatalf("all.log has %d lines, want 2: %q", len(lines), lines)
+	}
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(lines[1]), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["level"] != "error" || fields[logger.FieldTraceID] != "abc" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, fields["time"]); err != nil {
+		t.Errorf("time %q is not RFC3339Nano: %v", fields["time"], err)
+	}
+
+	lines = readLines(t, errors)
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "time=") || !strings.Contains(lines[0], ` level=error msg="Failed to start" trace_id=abc`) {
+		t.Errorf("unexpected errors.log %q", lines)
+	}
+}
+
+func TestConfigureUDPSink(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	l, closer, err := logger.Configure(config.LogConfig{
+		Format: config.LogJSON,
+		Sinks: map[string]config.LogSinkConfig{
+			"syslog": {Type: config.SinkUDP, Address: pc.LocalAddr().String()},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	l.Warn().Msg("Queue is slow")
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// local0.warning
+	if !strings.HasPrefix(msg, "<132>") || !strings.HasSuffix(msg, `"message":"Queue is slow"}`) {
+		t.Errorf("unexpected datagram %q", msg)
+	}
+}
+
+func TestConfigureFailsOnBadSink(t *testing.T) {
+	_, _, err := logger.Configure(config.LogConfig{
+		Sinks: map[string]config.LogSinkConfig{"out": {Type: config.SinkFile, Path: t.TempDir()}},
+	})
+	if err == nil {
+		t.Error("expected a directory to be rejected as a log file")
+	}
+}
+
+func TestProdProfileLogsJSON(t *testing.T) {
+	appConfig, err := config.ForEnv(config.Env.Prod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.Log.Format != config.LogJSON || appConfig.Log.TimeFormat != time.RFC3339Nano || appConfig.Log.Level != zerolog.InfoLevel {
+		t.Errorf("unexpected prod log config %+v", appConfig.Log)
+	}
+}
+
+func TestLoggersFromConfigShareSinks(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	appConfig := config.AppConfig{Env: config.Env.Test, Log: config.LogConfig{
+		Format: config.LogJSON,
+		Sinks:  map[string]config.LogSinkConfig{"syslog": {Type: config.SinkUDP, Address: pc.LocalAddr().String()}},
+	}}
+	from := func(l logger.Logger) string {
+		t.Helper()
+		l.Info().Msg("Started")
+		buf := make([]byte, 1024)
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr.String()
+	}
+
+	var first, second logger.Logger
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(appConfig),
+		logger.FxSinks(),
+		fx.Invoke(func(appConfig config.AppConfig) {
+			first = logger.NewLoggerFromConfig(appConfig)
+			second = logger.NewServiceLoggerFromConfig("cases", appConfig)
+		}),
+	)
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	shared := from(first)
+	if addr := from(second); addr != shared {
+		t.Errorf("the loggers send from %s and %s, want one connection", shared, addr)
+	}
+	if err := app.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sinks closed on stop are opened again for the next logger.
+	hold := logger.HoldSinks(appConfig.Log)
+	defer hold.Close()
+	if addr := from(logger.NewLoggerFromConfig(appConfig)); addr == shared {
+		t.Errorf("the connection %s was not closed on stop", addr)
+	}
+}
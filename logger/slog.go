@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/rs/zerolog"
+)
+
+// SlogHandler forwards the records of a slog.Logger to a Logger.
+type SlogHandler struct {
+	l Logger
+	// groups are the open groups, pending the attributes added in each of
+	// them after the top level ones of pending[0].
+	groups  []string
+	pending [][]slog.Attr
+}
+
+func NewSlogHandler(l Logger) *SlogHandler {
+	return &SlogHandler{l: l, pending: [][]slog.Attr{nil}}
+}
+
+// Slog returns a slog.Logger writing to l.
+func (l Logger) Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(l))
+}
+
+func slogLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
+		return zerolog.DebugLevel
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.l.Enabled(slogLevel(level))
+}
+
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	var attrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	e := h.l.WithLevel(slogLevel(r.Level))
+	addSlogAttrs(e, h.pending[0])
+	if len(h.groups) == 0 {
+		addSlogAttrs(e, attrs)
+	} else {
+		inner := zerolog.Dict()
+		depth := len(h.groups)
+		addSlogAttrs(inner, h.pending[depth])
+		addSlogAttrs(inner, attrs)
+		for depth--; depth > 0; depth-- {
+			outer := zerolog.Dict()
+			addSlogAttrs(outer, h.pending[depth])
+			inner = outer.Dict(h.groups[depth], inner)
+		}
+		e.Dict(h.groups[0], inner)
+	}
+	e.Msg(r.Message)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	c := h.clone()
+	last := len(c.pending) - 1
+	c.pending[last] = append(c.pending[last][:len(c.pending[last]):len(c.pending[last])], attrs...)
+	return c
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := h.clone()
+	c.groups = append(c.groups, name)
+	c.pending = append(c.pending, nil)
+	return c
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	return &SlogHandler{
+		l:       h.l,
+		groups:  h.groups[:len(h.groups):len(h.groups)],
+		pending: append([][]slog.Attr(nil), h.pending...),
+	}
+}
+
+func addSlogAttrs(e *zerolog.Event, attrs []slog.Attr) {
+	for _, a := range attrs {
+		addSlogAttr(e, a)
+	}
+}
+
+func addSlogAttr(e *zerolog.Event, a slog.Attr) {
+	v := a.Value.Resolve()
+	if a.Key == "" && v.Kind() != slog.KindGroup {
+		return
+	}
+	switch v.Kind() {
+	case slog.KindString:
+		e.Str(a.Key, v.String())
+	case slog.KindInt64:
+		e.Int64(a.Key, v.Int64())
+	case slog.KindUint64:
+		e.Uint64(a.Key, v.Uint64())
+	case slog.KindFloat64:
+		e.Float64(a.Key, v.Float64())
+	case slog.KindBool:
+		e.Bool(a.Key, v.Bool())
+	case slog.KindDuration:
+		e.Dur(a.Key, v.Duration())
+	case slog.KindTime:
+		e.Time(a.Key, v.Time())
+	case slog.KindGroup:
+		group := v.Group()
+		if len(group) == 0 {
+			return
+		}
+		// An unnamed group is inlined.
+		if a.Key == "" {
+			addSlogAttrs(e, group)
+			return
+		}
+		d := zerolog.Dict()
+		addSlogAttrs(d, group)
+		e.Dict(a.Key, d)
+	default:
+		if err, ok := v.Any().(error); ok {
+			e.AnErr(a.Key, err)
+		} else {
+			e.Interface(a.Key, v.Any())
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"log"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// StdLogger returns a standard library logger writing every line to l at
+// level, e.g. for http.Server.ErrorLog.
+func (l Logger) StdLogger(level zerolog.Level) *log.Logger {
+	return log.New(stdWriter{l: l, level: level}, "", 0)
+}
+
+type stdWriter struct {
+	l     Logger
+	level zerolog.Level
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.l.WithLevel(w.level).Msg(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"github.com/rs/zerolog"
+	"go.uber.org/fx/fxevent"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// zapCore forwards the entries of a zap.Logger to a Logger.
+type zapCore struct {
+	l Logger
+}
+
+// NewZapCore returns a zapcore.Core writing to l.
+func NewZapCore(l Logger) zapcore.Core {
+	return zapCore{l: l}
+}
+
+// Zap returns a zap.Logger writing to l.
+func (l Logger) Zap() *zap.Logger {
+	return zap.New(NewZapCore(l))
+}
+
+// NewFxEventLogger returns an fx event logger writing to l, for fx.WithLogger.
+func NewFxEventLogger(l Logger) fxevent.Logger {
+	return &fxevent.ZapLogger{Logger: l.Zap()}
+}
+
+func zapLevel(level zapcore.Level) zerolog.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return zerolog.DebugLevel
+	case zapcore.InfoLevel:
+		return zerolog.InfoLevel
+	case zapcore.WarnLevel:
+		return zerolog.WarnLevel
+	case zapcore.ErrorLevel, zapcore.DPanicLevel:
+		return zerolog.ErrorLevel
+	case zapcore.PanicLevel:
+		return zerolog.PanicLevel
+	case zapcore.FatalLevel:
+		return zerolog.FatalLevel
+	default:
+		return zerolog.TraceLevel
+	}
+}
+
+func zapFields(fields []zapcore.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+func (c zapCore) Enabled(level zapcore.Level) bool {
+	return c.l.Enabled(zapLevel(level))
+}
+
+func (c zapCore) With(fields []zapcore.Field) zapcore.Core {
+	if len(fields) == 0 {
+		return c
+	}
+	return zapCore{l: Logger{Logger: c.l.With().Fields(zapFields(fields)).Logger()}}
+}
+
+func (c zapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write logs the entry; zap itself panics or exits after the panic and fatal
+// entries.
+func (c zapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	e := c.l.WithLevel(zapLevel(ent.Level))
+	if ent.LoggerName != "" {
+		e = e.Str("logger", ent.LoggerName)
+	}
+	if len(fields) > 0 {
+		e = e.Fields(zapFields(fields))
+	}
+	e.Msg(ent.Message)
+	return nil
+}
+
+func (c zapCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,140 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+)
+
+// Dialect holds the SQL differences between the supported databases.
+type Dialect interface {
+	// Placeholder returns the bind parameter for the n-th argument, starting at 1.
+	Placeholder(n int) string
+	// Lock blocks until conn holds the migration lock named after the version table.
+	Lock(ctx context.Context, conn *sql.Conn, name string) error
+	Unlock(ctx context.Context, conn *sql.Conn, name string) error
+	// TableExists tells whether the table is in the current schema.
+	TableExists(ctx context.Context, conn *sql.Conn, name string) (bool, error)
+}
+
+func queryExists(ctx context.Context, conn *sql.Conn, query string, args ...interface{}) (bool, error) {
+	var exists bool
+	err := conn.QueryRowContext(ctx, query, args...).Scan(&exists)
+	return exists, err
+}
+
+func DialectFor(databaseType config.DatabaseType) (Dialect, error) {
+	switch databaseType {
+	case config.Sqlite:
+		return SqliteDialect{}, nil
+	case config.Postgres:
+		return PostgresDialect{}, nil
+	case config.MySQL:
+		return MySQLDialect{}, nil
+	default:
+		return nil, fmt.Errorf("no migration dialect for database type %v", databaseType)
+	}
+}
+
+// lockRetryInterval is how often a lock held by another replica is retried.
+const lockRetryInterval = 50 * time.Millisecond
+
+// SqliteDialect has no advisory locks, the lock is a row in a dedicated table.
+// A replica that crashed while migrating leaves the row behind and it has to
+// be deleted by hand.
+type SqliteDialect struct{}
+
+func (SqliteDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (SqliteDialect) Lock(ctx context.Context, conn *sql.Conn, name string) error {
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s_lock (id INTEGER PRIMARY KEY, locked_at TIMESTAMP NOT NULL)", name)); err != nil {
+		return err
+	}
+	for {
+		_, err := conn.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s_lock (id, locked_at) VALUES (1, ?)", name), time.Now().UTC())
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+func (SqliteDialect) Unlock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s_lock WHERE id = 1", name))
+	return err
+}
+
+func (SqliteDialect) TableExists(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	return queryExists(ctx, conn, "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?", name)
+}
+
+// PostgresDialect uses a session-level advisory lock keyed by the table name.
+type PostgresDialect struct{}
+
+func (PostgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+func (PostgresDialect) Lock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey(name))
+	return err
+}
+
+func (PostgresDialect) Unlock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockKey(name))
+	return err
+}
+
+func (PostgresDialect) TableExists(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	return queryExists(ctx, conn, "SELECT to_regclass($1) IS NOT NULL", name)
+}
+
+// MySQLDialect uses GET_LOCK, which is held by the connection.
+type MySQLDialect struct{}
+
+func (MySQLDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (MySQLDialect) Lock(ctx context.Context, conn *sql.Conn, name string) error {
+	for {
+		var got sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 1)", name).Scan(&got); err != nil {
+			return err
+		}
+		if got.Valid && got.Int64 == 1 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+func (MySQLDialect) Unlock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name)
+	return err
+}
+
+func (MySQLDialect) TableExists(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	return queryExists(ctx, conn,
+		"SELECT COUNT(*) > 0 FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", name)
+}
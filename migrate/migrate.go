@@ -0,0 +1,322 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/logger"
+	"github.com/rs/zerolog"
+)
+
+// Latest migrates up to the highest registered version.
+const Latest int64 = -1
+
+const (
+	DefaultTable       = "schema_migrations"
+	DefaultLockTimeout = time.Minute
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down reverts Up. Migrations without it cannot be rolled back.
+	Down string
+}
+
+// Set is the ordered collection of migrations a service registers for its schema.
+type Set struct {
+	mu         sync.RWMutex
+	migrations map[int64]Migration
+}
+
+func NewSet(migrations ...Migration) (*Set, error) {
+	s := &Set{migrations: map[int64]Migration{}}
+	for _, m := range migrations {
+		if err := s.Register(m); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Set) Register(m Migration) error {
+	if m.Version <= 0 {
+		return fmt.Errorf("migration %q: version must be > 0", m.Name)
+	}
+	if m.Up == "" {
+		return fmt.Errorf("migration %d %q: empty up statement", m.Version, m.Name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.migrations[m.Version]; ok {
+		return fmt.Errorf("migration %d %q: version already used by %q", m.Version, m.Name, prev.Name)
+	}
+	s.migrations[m.Version] = m
+	return nil
+}
+
+// Migrations returns the registered migrations by ascending version.
+func (s *Set) Migrations() []Migration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ms := make([]Migration, 0, len(s.migrations))
+	for _, m := range s.migrations {
+		ms = append(ms, m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms
+}
+
+type Direction string
+
+const (
+	Up   Direction = "up"
+	Down Direction = "down"
+)
+
+// Step is a migration to apply in a given direction.
+type Step struct {
+	Migration
+	Direction Direction
+}
+
+func (s Step) Statement() string {
+	if s.Direction == Down {
+		return s.Down
+	}
+	return s.Up
+}
+
+type Migrator struct {
+	Set         *Set
+	Dialect     Dialect
+	Table       string
+	LockTimeout time.Duration
+	Logger      logger.Logger
+}
+
+// New returns a Migrator for the given database type using the default
+// version table and lock timeout.
+func New(databaseType config.DatabaseType, set *Set) (*Migrator, error) {
+	dialect, err := DialectFor(databaseType)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		Set:         set,
+		Dialect:     dialect,
+		Table:       DefaultTable,
+		LockTimeout: DefaultLockTimeout,
+		Logger:      logger.Logger{Logger: zerolog.Nop()},
+	}, nil
+}
+
+// Migrate applies or reverts migrations until the schema is at target, which
+// is a migration version, 0 to revert everything, or Latest. Each migration
+// runs in its own transaction while holding the migration lock, so replicas
+// booting at the same time apply every migration exactly once.
+func (m *Migrator) Migrate(ctx context.Context, db *sql.DB, target int64) error {
+	return m.withLock(ctx, db, func(conn *sql.Conn) error {
+		steps, err := m.plan(ctx, conn, target)
+		if err != nil {
+			return err
+		}
+		for _, step := range steps {
+			if err := m.apply(ctx, conn, step); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Plan returns the steps Migrate would run to reach target. It only reads
+// the database, so that it also works for a read-only role.
+func (m *Migrator) Plan(ctx context.Context, db *sql.DB, target int64) ([]Step, error) {
+	var steps []Step
+	err := m.withConn(ctx, db, func(conn *sql.Conn) error {
+		var err error
+		steps, err = m.plan(ctx, conn, target)
+		return err
+	})
+	return steps, err
+}
+
+// DryRun writes the statements Migrate would run to reach target, without running them.
+func (m *Migrator) DryRun(ctx context.Context, db *sql.DB, target int64, w io.Writer) error {
+	steps, err := m.Plan(ctx, db, target)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		_, err := fmt.Fprintln(w, "-- schema is up to date")
+		return err
+	}
+	for _, step := range steps {
+		if _, err := fmt.Fprintf(w, "-- %d %s (%s)\n%s\n\n", step.Version, step.Name, step.Direction, step.Statement()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Version returns the highest applied migration, or 0 on an empty schema.
+func (m *Migrator) Version(ctx context.Context, db *sql.DB) (int64, error) {
+	var version int64
+	err := m.withConn(ctx, db, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		for v := range applied {
+			if v > version {
+				version = v
+			}
+		}
+		return err
+	})
+	return version, err
+}
+
+func (m *Migrator) withConn(ctx context.Context, db *sql.DB, fn func(*sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return fn(conn)
+}
+
+func (m *Migrator) withLock(ctx context.Context, db *sql.DB, fn func(*sql.Conn) error) error {
+	return m.withConn(ctx, db, func(conn *sql.Conn) error {
+		lockCtx := ctx
+		if m.LockTimeout > 0 {
+			var cancel context.CancelFunc
+			lockCtx, cancel = context.WithTimeout(ctx, m.LockTimeout)
+			defer cancel()
+		}
+		if err := m.Dialect.Lock(lockCtx, conn, m.Table); err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+		defer func() {
+			if err := m.Dialect.Unlock(context.Background(), conn, m.Table); err != nil {
+				m.Logger.Error().Err(err).Str("table", m.Table).Msg("Failed to release the migration lock")
+			}
+		}()
+		if err := m.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)",
+		m.Table))
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", m.Table, err)
+	}
+	return nil
+}
+
+// applied returns the applied versions, none when the version table does
+// not exist yet.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	exists, err := m.Dialect.TableExists(ctx, conn, m.Table)
+	if err != nil {
+		return nil, fmt.Errorf("looking up %s: %w", m.Table, err)
+	}
+	if !exists {
+		return map[int64]bool{}, nil
+	}
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s", m.Table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) plan(ctx context.Context, conn *sql.Conn, target int64) ([]Step, error) {
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	migrations := m.Set.Migrations()
+	if target == Latest {
+		target = 0
+		if len(migrations) > 0 {
+			target = migrations[len(migrations)-1].Version
+		}
+	}
+	if target < 0 {
+		return nil, fmt.Errorf("invalid target version %d", target)
+	}
+
+	known := map[int64]bool{}
+	var steps []Step
+	for _, mig := range migrations {
+		known[mig.Version] = true
+		if mig.Version <= target && !applied[mig.Version] {
+			steps = append(steps, Step{Migration: mig, Direction: Up})
+		}
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mig := migrations[i]
+		if mig.Version > target && applied[mig.Version] {
+			if mig.Down == "" {
+				return nil, fmt.Errorf("migration %d %s cannot be reverted: no down statement", mig.Version, mig.Name)
+			}
+			steps = append(steps, Step{Migration: mig, Direction: Down})
+		}
+	}
+	for v := range applied {
+		if v > target && !known[v] {
+			return nil, fmt.Errorf("applied migration %d is not registered and cannot be reverted", v)
+		}
+	}
+	return steps, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, step Step) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, step.Statement()); err != nil {
+		return fmt.Errorf("migration %d %s (%s): %w", step.Version, step.Name, step.Direction, err)
+	}
+	if step.Direction == Up {
+		_, err = tx.ExecContext(ctx,
+			fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (%s, %s, %s)",
+				m.Table, m.Dialect.Placeholder(1), m.Dialect.Placeholder(2), m.Dialect.Placeholder(3)),
+			step.Version, step.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE version = %s", m.Table, m.Dialect.Placeholder(1)),
+			step.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("recording migration %d %s: %w", step.Version, step.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	m.Logger.Info().Int64("version", step.Version).Str("migration", step.Name).Str("direction", string(step.Direction)).Msg("Applied migration")
+	return nil
+}
@@ -0,0 +1,16 @@
+package migrate_test
+
+import (
+	"testing"
+
+	"github.com/case-management-suite/common/migrate"
+)
+
+func TestSetRejectsInvalidMigrations(t *testing.T) {
+	if _, err := migrate.NewSet(migrate.Migration{Version: 1, Name: "a", Up: "x"}, migrate.Migration{Version: 1, Name: "b", Up: "y"}); err == nil {
+		t.Error("expected duplicate versions to fail")
+	}
+	if _, err := migrate.NewSet(migrate.Migration{Version: 0, Name: "zero", Up: "x"}); err == nil {
+		t.Error("expected version 0 to fail")
+	}
+}
@@ -0,0 +1,25 @@
+module github.com/case-management-suite/common/migrate/sqlitetest
+
+go 1.21
+
+require (
+	github.com/case-management-suite/common v0.0.0
+	github.com/mattn/go-sqlite3 v1.14.16
+)
+
+require (
+	github.com/BurntSushi/toml v1.2.1 // indirect
+	github.com/fsnotify/fsnotify v1.6.0 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.17 // indirect
+	github.com/rs/zerolog v1.28.0 // indirect
+	go.uber.org/atomic v1.10.0 // indirect
+	go.uber.org/dig v1.16.0 // indirect
+	go.uber.org/fx v1.19.0 // indirect
+	go.uber.org/multierr v1.9.0 // indirect
+	go.uber.org/zap v1.24.0 // indirect
+	golang.org/x/sys v0.3.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/case-management-suite/common => ../..
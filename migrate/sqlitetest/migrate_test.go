@@ -0,0 +1,225 @@
+// Package sqlitetest runs the migrations against SQLite. It is a module of
+// its own so that the cgo driver stays out of the requirements of common.
+package sqlitetest_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/case-management-suite/common/config"
+	"github.com/case-management-suite/common/migrate"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var casesMigrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "create_cases",
+		Up:      "create table cases(case_id INTEGER PRIMARY KEY, status TEXT);",
+		Down:    "drop table cases;",
+	},
+	{
+		Version: 2,
+		Name:    "add_cases_owner",
+		Up:      "alter table cases add column owner TEXT;",
+		Down:    "alter table cases drop column owner;",
+	},
+	{
+		Version: 3,
+		Name:    "create_case_events",
+		Up:      "create table case_events(event_id INTEGER PRIMARY KEY, case_id INTEGER, action TEXT);",
+		Down:    "drop table case_events;",
+	},
+}
+
+func openSqlite(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	storage := config.DatabaseConfig{
+		DatabaseType: config.Sqlite,
+		Sqlite:       config.SqliteConfig{Path: path, BusyTimeout: 5 * time.Second},
+	}
+	dsn, err := storage.DSN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver, _ := storage.DriverName()
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newMigrator(t *testing.T) *migrate.Migrator {
+	t.Helper()
+	set, err := migrate.NewSet(casesMigrations...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.New(config.Sqlite, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("select count(*) from sqlite_master where type = 'table' and name = ?", name).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n == 1
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := openSqlite(t, filepath.Join(t.TempDir(), "cases.db"))
+	m := newMigrator(t)
+
+	if err := m.Migrate(ctx, db, 2); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Version(ctx, db); v != 2 {
+		t.Errorf("Version() = %d, want 2", v)
+	}
+	if _, err := db.Exec("insert into cases(case_id, status, owner) values (1, 'OPEN', 'ana')"); err != nil {
+		t.Errorf("migration 2 was not applied: %v", err)
+	}
+
+	if err := m.Migrate(ctx, db, migrate.Latest); err != nil {
+		t.Fatal(err)
+	}
+	if !tableExists(t, db, "case_events") {
+		t.Error("migration 3 was not applied")
+	}
+
+	if err := m.Migrate(ctx, db, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Version(ctx, db); v != 1 {
+		t.Errorf("Version() = %d, want 1", v)
+	}
+	if tableExists(t, db, "case_events") || !tableExists(t, db, "cases") {
+		t.Error("migrations 3 and 2 were not reverted")
+	}
+
+	if err := m.Migrate(ctx, db, 0); err != nil {
+		t.Fatal(err)
+	}
+	if tableExists(t, db, "cases") {
+		t.Error("migration 1 was not reverted")
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	ctx := context.Background()
+	db := openSqlite(t, filepath.Join(t.TempDir(), "cases.db"))
+	m := newMigrator(t)
+	if err := m.Migrate(ctx, db, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := m.DryRun(ctx, db, migrate.Latest, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"-- 2 add_cases_owner (up)", "-- 3 create_case_events (up)", "create table case_events"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dry run output misses %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "create_cases") {
+		t.Errorf("dry run lists an applied migration:\n%s", out.String())
+	}
+	if tableExists(t, db, "case_events") {
+		t.Error("dry run applied migrations")
+	}
+}
+
+func TestDryRunLeavesEmptySchemaUntouched(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cases.db")
+	db := openSqlite(t, path)
+	m := newMigrator(t)
+
+	var out bytes.Buffer
+	if err := m.DryRun(ctx, db, migrate.Latest, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "-- 1 create_cases (up)") {
+		t.Errorf("dry run output misses the first migration:\n%s", out.String())
+	}
+	if tableExists(t, db, migrate.DefaultTable) {
+		t.Errorf("dry run created %s", migrate.DefaultTable)
+	}
+	if v, err := m.Version(ctx, db); err != nil || v != 0 {
+		t.Errorf("Version() = %d, %v, want 0", v, err)
+	}
+	if tableExists(t, db, migrate.DefaultTable) {
+		t.Errorf("Version created %s", migrate.DefaultTable)
+	}
+
+	// A read-only connection is enough to preview the migrations.
+	readOnly, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer readOnly.Close()
+	if err := m.DryRun(ctx, readOnly, migrate.Latest, &out); err != nil {
+		t.Errorf("dry run on a read-only connection: %v", err)
+	}
+}
+
+func TestMigrateConcurrentReplicas(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cases.db")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		db := openSqlite(t, path)
+		m := newMigrator(t)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- m.Migrate(ctx, db, migrate.Latest)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	db := openSqlite(t, path)
+	var applied int
+	if err := db.QueryRow("select count(*) from " + migrate.DefaultTable).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(casesMigrations) {
+		t.Errorf("%d migrations recorded, want %d", applied, len(casesMigrations))
+	}
+}
+
+func TestIrreversibleMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openSqlite(t, filepath.Join(t.TempDir(), "cases.db"))
+	set, _ := migrate.NewSet(migrate.Migration{Version: 1, Name: "create_cases", Up: "create table cases(case_id INTEGER PRIMARY KEY);"})
+	m, _ := migrate.New(config.Sqlite, set)
+	if err := m.Migrate(ctx, db, migrate.Latest); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Migrate(ctx, db, 0); err == nil {
+		t.Error("expected reverting a migration without down statement to fail")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/case-management-suite/common/config"
 	"github.com/case-management-suite/common/ctxutils"
 	"github.com/case-management-suite/common/logger"
+	"github.com/rs/zerolog"
 	"go.uber.org/fx"
 )
 
@@ -41,21 +42,32 @@ type Server[T Serveable] struct {
 	Logger        logger.Logger
 	ServerMetrics ServerMetrics
 	Server        T
+	// logConfig is the Log section of the sinks of Logger, held while the
+	// server runs in fx.
+	logConfig *config.LogConfig
 }
 
 func (s *Server[T]) logServerInfo(msg string) {
+	s.serverEvent(s.Logger.Info()).Msg(msg)
+}
+
+func (s *Server[T]) logServerError(msg string, err error) {
+	logger.WithError(s.serverEvent(s.Logger.Error()), err).Msg(msg)
+}
+
+func (s *Server[T]) serverEvent(e *zerolog.Event) *zerolog.Event {
 	serverName := s.Server.GetName()
 	serverInfo := s.Server.GetServerConfig()
-	l := s.Logger.Info().Str("server_name", serverName)
+	e = e.Str("server_name", serverName)
 	if serverInfo != nil {
 		stype := serverInfo.Type
-		l = l.Str("server_type", string(stype))
+		e = e.Str("server_type", string(stype))
 		switch stype {
 		case HttpServerType, GRPCServerType:
-			l = l.Str("host", serverInfo.Host).Int("port", serverInfo.Port)
+			e = e.Str("host", serverInfo.Host).Int("port", serverInfo.Port)
 		}
 	}
-	l.Msg(msg)
+	return e
 }
 
 func (s *Server[T]) Start(ctx context.Context) error {
@@ -65,7 +77,7 @@ func (s *Server[T]) Start(ctx context.Context) error {
 	ctx = ctxutils.DecorateContext(ctx, ctxutils.ContextDecoration{Name: serverName})
 	err := s.Server.Start(ctx)
 	if err != nil {
-		s.logServerInfo("Failed to start")
+		s.logServerError("Failed to start", err)
 	} else {
 		s.logServerInfo("Started")
 	}
@@ -76,7 +88,7 @@ func (s *Server[T]) Stop(ctx context.Context) error {
 	s.logServerInfo("Stopping server...")
 	err := s.Server.Stop(ctx)
 	if err != nil {
-		s.logServerInfo("Failed to stop")
+		s.logServerError("Failed to stop", err)
 	} else {
 		s.logServerInfo("Stopped")
 	}
@@ -84,9 +96,20 @@ func (s *Server[T]) Stop(ctx context.Context) error {
 }
 
 func (s *Server[T]) fxServer(lc fx.Lifecycle) {
+	stop := s.Stop
+	if s.logConfig != nil {
+		hold := logger.HoldSinks(*s.logConfig)
+		stop = func(ctx context.Context) error {
+			err := s.Stop(ctx)
+			if cerr := hold.Close(); err == nil {
+				err = cerr
+			}
+			return err
+		}
+	}
 	lc.Append(fx.Hook{
 		OnStart: s.Start,
-		OnStop:  s.Stop,
+		OnStop:  stop,
 	})
 }
 
@@ -99,9 +122,9 @@ func (s *Server[T]) GetFxOption() fx.Option {
 type factoryFn[T Serveable] func(ServerUtils) T
 
 func NewServer[T Serveable](factory factoryFn[T], appConfig config.AppConfig) Server[T] {
-	l := logger.NewLogger(appConfig.Env)
+	l := logger.NewLoggerFromConfig(appConfig)
 	params := ServerUtils{Logger: l}
-	return Server[T]{Server: factory(params), Logger: l}
+	return Server[T]{Server: factory(params), Logger: l, logConfig: &appConfig.Log}
 }
 
 type ServerUtils struct {
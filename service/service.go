@@ -1,14 +1,19 @@
 package service
 
 import (
+	"context"
+
+	"github.com/case-management-suite/common/audit"
 	"github.com/case-management-suite/common/config"
 	"github.com/case-management-suite/common/logger"
 	"github.com/case-management-suite/common/server"
+	"go.uber.org/fx"
 )
 
 type ServiceUtils struct {
 	IsSet  bool
 	Logger logger.Logger
+	Audit  audit.AuditLogger
 }
 
 func (*ServiceUtils) mustImplementServiceable() {}
@@ -16,18 +21,22 @@ func (*ServiceUtils) mustImplementServiceable() {}
 func (su *ServiceUtils) clone(other ServiceUtils) {
 	su.Logger = other.Logger
 	su.IsSet = other.IsSet
+	su.Audit = other.Audit
 }
 
 func NewTestServiceUtils() ServiceUtils {
-	return ServiceUtils{IsSet: true, Logger: logger.NewTestLogger()}
+	return ServiceUtils{IsSet: true, Logger: logger.NewTestLogger(), Audit: audit.NewMemoryLogger()}
 }
 
+// NewServiceUtils returns the utils of serviceName, whose Audit fails every
+// event until it is set.
 func NewServiceUtils(serviceName string, appConfig config.AppConfig) ServiceUtils {
-	return ServiceUtils{IsSet: true, Logger: logger.NewServiceLogger(serviceName, appConfig.Env)}
+	return ServiceUtils{IsSet: true, Logger: logger.NewServiceLoggerFromConfig(serviceName, appConfig), Audit: audit.Unconfigured}
 }
 
+// NewServiceUtilsFromServerUtils is NewServiceUtils with the logger of utls.
 func NewServiceUtilsFromServerUtils(utls server.ServerUtils) ServiceUtils {
-	return ServiceUtils{IsSet: true, Logger: utls.Logger}
+	return ServiceUtils{IsSet: true, Logger: utls.Logger, Audit: audit.Unconfigured}
 }
 
 type Serviceable interface {
@@ -39,23 +48,34 @@ type Service[T Serviceable] struct {
 	Value T
 }
 
+// NewService returns the Service of serviceable, whose audit events fail
+// with audit.ErrNotConfigured, see NewAuditedService.
 func NewService[T Serviceable](appConfig config.AppConfig, serviceName string, serviceable T) Service[T] {
-	serviceable.clone(NewServiceUtils(serviceName, appConfig))
-	return Service[T]{Value: serviceable}
+	return NewAuditedService(appConfig, serviceName, serviceable, audit.Unconfigured)
 }
 
-// type FxServiceInput struct {
-// 	fx.In
-// 	AppConfig config.AppConfig
-// }
-
-// type FxServiceOutput[T Serviceable] struct {
-// 	fx.Out
-// 	Service Service[T]
-// }
-
-// type FxServiceFactory func()
+// NewAuditedService is NewService recording the audit events with auditLogger.
+func NewAuditedService[T Serviceable](appConfig config.AppConfig, serviceName string, serviceable T, auditLogger audit.AuditLogger) Service[T] {
+	utils := NewServiceUtils(serviceName, appConfig)
+	utils.Audit = auditLogger
+	serviceable.clone(utils)
+	return Service[T]{Value: serviceable}
+}
 
-// func NewFxService[T Serviceable](serviceName, opts fx.Option) fx.Option {
+type fxServiceParams struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	AppConfig config.AppConfig
+	Audit     audit.AuditLogger
+}
 
-// }
+// FxService provides the Service of serviceable, built like NewAuditedService
+// with the AuditLogger of the application, such as the one of audit.FxAudit.
+// The sinks of its logger are held until the application stops.
+func FxService[T Serviceable](serviceName string, serviceable T) fx.Option {
+	return fx.Provide(func(p fxServiceParams) Service[T] {
+		hold := logger.HoldSinks(p.AppConfig.Log)
+		p.Lifecycle.Append(fx.Hook{OnStop: func(context.Context) error { return hold.Close() }})
+		return NewAuditedService(p.AppConfig, serviceName, serviceable, p.Audit)
+	})
+}
@@ -1,8 +1,11 @@
 package service_test
 
 import (
+	"context"
+	"errors"
 	"testing"
 
+	"github.com/case-management-suite/common/audit"
 	"github.com/case-management-suite/common/config"
 	"github.com/case-management-suite/common/service"
 	"github.com/case-management-suite/testutil"
@@ -24,3 +27,18 @@ func TestXxx(t *testing.T) {
 	ms.Value.Logger.Info().Msg("Worked!")
 	testutil.AssertTrue(ms.Value.IsSet, t)
 }
+
+func TestServiceAudit(t *testing.T) {
+	event := audit.Event{Actor: "alice", Action: "open", CaseID: "case-1"}
+	unaudited := service.NewService(config.NewLocalTestAppConfig(), "myservice", &MyData{})
+	if err := unaudited.Value.Audit.Log(context.Background(), event); !errors.Is(err, audit.ErrNotConfigured) {
+		t.Errorf("Log without audit logger = %v, want ErrNotConfigured", err)
+	}
+
+	l := audit.NewMemoryLogger()
+	audited := service.NewAuditedService(config.NewLocalTestAppConfig(), "myservice", &MyData{}, l)
+	if err := audited.Value.Audit.Log(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertTrue(len(l.Records()) == 1, t)
+}
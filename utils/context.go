@@ -5,8 +5,8 @@ import (
 
 	"os"
 
+	"github.com/case-management-suite/common/ctxutils"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
 type ContextData string
@@ -55,13 +55,10 @@ func IsProdContext(ctx context.Context) bool {
 }
 
 func DecorateContext(ctx context.Context, serviceName string) context.Context {
-	defaultLogger := log.Output(zerolog.ConsoleWriter{
-		Out:     os.Stderr,
-		NoColor: false,
-	}).With().Caller().Logger()
-	zerolog.DefaultContextLogger = &defaultLogger
-
 	ctx = context.WithValue(ctx, ServiceName, ContextDataValue{serviceName})
+	if _, ok := ctxutils.ContextLogger(ctx); !ok {
+		ctx = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Caller().Logger().WithContext(ctx)
+	}
 
 	if IsTestContext(ctx) {
 		ctx = zerolog.Ctx(ctx).With().Str("execution", "test").Logger().WithContext(ctx)